@@ -3,7 +3,9 @@ package zttp
 import (
 	"bufio"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"strings"
@@ -14,8 +16,73 @@ type App struct {
 	*Router
 	Routers         []*Router
 	PrettyPrintJSON bool
+	PrettyPrintXML  bool
+
+	// The JSON codec of `res.Json()` and `req.ParseJson()`, nil means `encoding/json`
+	// Swap in `zttp.JSONCodec{DisallowUnknownFields: true}` for stricter decoding, or any other codec
+	JSONEncoder JSONEncoder
+	JSONDecoder JSONDecoder
+
+	// Send the framework's own error responses, like 404 for unmatched routes and 413 for large bodies,
+	// as RFC 9457 problem details instead of plain text
+	ProblemDetails bool
+
+	// Stream request bodies from the socket through `req.BodyReader()` instead of
+	// buffering them into `req.Body` before the handler runs
+	// `req.Body` is then filled only once a body helper like `req.BodyString()` or `req.ParseJson()` reads it
+	StreamRequestBody bool
+
+	// Max request body size, requests declaring a larger body are rejected with 413
+	// Zero or negative means no limit, routes can override it with `route.BodyLimit()`
+	// Multipart bodies are exempt from it, and limited by `MaxMultipartSize` unless the route has a limit
+	BodyLimit int64
+	// Max number of request header fields, more are rejected with 431
+	MaxHeaderCount int
+	// Max length of a single request header line in bytes, longer lines are rejected with 431
+	// The request line is limited to it too, and rejected with 414 if it's longer
+	MaxHeaderLineLength int
+
+	// Multipart bytes kept in memory before spooling the files to temp files
+	// Plain values are never spooled, so forms whose values exceed it fail with `ErrMultipartTooLarge`
+	MultipartMemory int64
+	// Max size of a single multipart file, zero means no limit
+	MaxFileSize int64
+	// Max size of the whole multipart body, larger bodies are rejected with 413 (128 MB by default)
+	// Zero or negative means no limit
+	MaxMultipartSize int64
+
+	// Guard against open redirects, allowing `res.Redirect()` only to relative URLs,
+	// the host of the request, and the `RedirectAllowedHosts`
+	SafeRedirects bool
+	// Hosts redirects may target besides the request host when `SafeRedirects` is enabled
+	// An entry with a port only allows that port, like "example.com:8443"
+	RedirectAllowedHosts []string
+
+	// Keys of `res.SetSignedCookie()`, the first one signs and every one verifies,
+	// so a new key can be put first while cookies signed with the old ones are still accepted
+	CookieSigningKeys [][]byte
+	// Keys of `res.SetEncryptedCookie()`, 16, 24, or 32 bytes for AES-128, AES-192, or AES-256
+	// The first one encrypts and every one decrypts, rotated like `CookieSigningKeys`
+	CookieEncryptionKeys [][]byte
+
+	// The view engine of `res.Render()`, like `zttp.NewHTMLEngine("./views")`
+	Views ViewEngine
+	// The layout wrapping every rendered view, unless `res.Render()` is passed another one
+	ViewLayout string
 }
 
+const (
+	// Max request body size of the app, unless configured otherwise (4 MB)
+	DefaultBodyLimit = 4 << 20
+	// Max number of request header fields, unless configured otherwise
+	DefaultMaxHeaderCount = 100
+	// Max length of a request header line, unless configured otherwise (8 KB)
+	DefaultMaxHeaderLineLength = 8 << 10
+)
+
+// How long reading the next request, or the next piece of a streamed body, may take
+const readTimeout = 5 * time.Second
+
 type Ctx struct {
 	Req *Req
 	Res *Res
@@ -24,16 +91,23 @@ type Ctx struct {
 // New App constructor
 func NewApp() *App {
 	defaultRouter := &Router{
-		getRoutes:    []Route{},
-		postRoutes:   []Route{},
-		deleteRoutes: []Route{},
-		putRoutes:    []Route{},
-		patchRoutes:  []Route{},
+		getRoutes:    []*Route{},
+		postRoutes:   []*Route{},
+		deleteRoutes: []*Route{},
+		putRoutes:    []*Route{},
+		patchRoutes:  []*Route{},
 		middlewares:  []MiddlewareWrapper{},
 	}
 	app := &App{
 		Router:  defaultRouter,
 		Routers: []*Router{defaultRouter},
+
+		MultipartMemory:  DefaultMultipartMemory,
+		MaxMultipartSize: DefaultMaxMultipartSize,
+
+		BodyLimit:           DefaultBodyLimit,
+		MaxHeaderCount:      DefaultMaxHeaderCount,
+		MaxHeaderLineLength: DefaultMaxHeaderLineLength,
 	}
 
 	defaultRouter.App = app
@@ -45,11 +119,11 @@ func (app *App) NewRouter(path string) *Router {
 	router := &Router{
 		App:          app,
 		prefix:       path,
-		getRoutes:    []Route{},
-		postRoutes:   []Route{},
-		deleteRoutes: []Route{},
-		putRoutes:    []Route{},
-		patchRoutes:  []Route{},
+		getRoutes:    []*Route{},
+		postRoutes:   []*Route{},
+		deleteRoutes: []*Route{},
+		putRoutes:    []*Route{},
+		patchRoutes:  []*Route{},
 		middlewares:  []MiddlewareWrapper{},
 	}
 
@@ -58,6 +132,20 @@ func (app *App) NewRouter(path string) *Router {
 	return router
 }
 
+// Return the body limit of the matched route, falling back to the app's limit
+// Multipart bodies are streamed, so they fall back to the larger `MaxMultipartSize` instead
+func (app *App) bodyLimit(route *Route, multipart bool) int64 {
+	if route != nil && route.bodyLimit != 0 {
+		return route.bodyLimit
+	}
+
+	if multipart {
+		return app.MaxMultipartSize
+	}
+
+	return app.BodyLimit
+}
+
 // Start listening to the given port
 func (app *App) Start(port int) {
 
@@ -115,14 +203,41 @@ func (app *App) StartTls(port int, certFile, keyFile string) {
 	}
 }
 
+// Reads a streamed request body, extending the read deadline of the connection before every read,
+// so a large upload only times out when the client stops sending, not after `readTimeout` in total
+type deadlineReader struct {
+	r    io.Reader
+	conn net.Conn
+}
+
+func (d *deadlineReader) Read(p []byte) (int, error) {
+	if err := d.conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+		return 0, err
+	}
+
+	return d.r.Read(p)
+}
+
+// Response headers telling the client that the connection will be closed
+func closeHeaders() Header {
+	return Header{"Connection": {"close"}}
+}
+
 // The Front Controller
 // This function is responsible for handling the incoming request from the client tcp socket
 // from the beginning until it sends a response and close the connection eventually
 func handleClient(socket net.Conn, app *App) {
+	// The response of the current request, if a route matched
+	var res *Res
+
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("Recovered from panic: %v", r)
-			sendResponse(socket, []byte("Internal Server Error"), 500, "text/plain", nil)
+
+			// A committed response was already partly written, so the connection is only closed
+			if res == nil || !res.IsCommitted() {
+				sendError(socket, app, 500, nil)
+			}
 		}
 		socket.Close()
 	}()
@@ -131,15 +246,17 @@ func handleClient(socket net.Conn, app *App) {
 	rdr := bufio.NewReader(socket)
 
 	for {
+		res = nil
+
 		// Set hard-coded read timeout for now
 		// TODO: Make it an app's config specification later
-		if err := socket.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		if err := socket.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
 			log.Printf("Error setting read deadline: %v", err)
 			return
 		}
 
 		// Extract the request line, headers, and body
-		requestParts := extractRequestLine(rdr, socket)
+		requestParts := extractRequestLine(rdr, socket, app)
 		// TODO: make extractRequestLine() return []string, bool instead
 		// NOTE: THIS WAS ADDED TO AVOID EMPTY TCP CONNECTIONS MADE BY POSTMAN
 		// I think this is somehow related to the keep-alive request header
@@ -149,8 +266,18 @@ func handleClient(socket net.Conn, app *App) {
 			return
 		}
 
-		headers, contentLength := extractHeaders(rdr)
-		body := extractBody(rdr, contentLength)
+		headers, contentLength, err := extractHeaders(rdr, app.MaxHeaderCount, app.MaxHeaderLineLength)
+		if err != nil {
+			// The rest of the request can't be trusted, so respond if possible and close the connection
+			switch {
+			case errors.Is(err, errHeadersTooLarge):
+				sendError(socket, app, 431, closeHeaders())
+			case errors.Is(err, errInvalidContentLength):
+				sendError(socket, app, 400, closeHeaders())
+			}
+			return
+		}
+
 		cookies := extractCookies(headers)
 
 		// Extract the method and the raw path from the request line
@@ -175,13 +302,41 @@ func handleClient(socket net.Conn, app *App) {
 			queries = extractQueries(split[1])
 		}
 
-		// Find the matched handler from the router with parsing params, if exist
-		handler, params := findHandler(method, path, socket, app)
+		// Find the matched route from the router with parsing params, if exist
+		route, params, supported := findHandler(method, path, app)
+
+		// The body of an unsupported method is left unread, so the connection can't be reused
+		if !supported {
+			allow := closeHeaders()
+			allow.Set("Allow", allowedMethods)
+			sendError(socket, app, 405, allow)
+			return
+		}
+
+		// Reject bodies larger than the route's limit before reading or allocating anything
+		// The body is left unread, so the connection can't be reused
+		multipart := isMultipart(headers)
+		if limit := app.bodyLimit(route, multipart); limit > 0 && int64(contentLength) > limit {
+			sendError(socket, app, 413, closeHeaders())
+			return
+		}
+
+		// Multipart bodies, and all the bodies if the app streams them, are read from the socket
+		// only when the handler reads them, instead of buffering them
+		body := ""
+		var bodyStream io.Reader
+		if app.StreamRequestBody || multipart {
+			bodyStream = &deadlineReader{r: io.LimitReader(rdr, int64(contentLength)), conn: socket}
+		} else {
+			body = extractBody(rdr, contentLength)
+		}
 
-		// If a handler matched, call it with the generated request and response objects
+		// If a route matched, call its handler with the generated request and response objects
 		// Otherwise, send a 404 not found response
-		if handler != nil {
+		if route != nil {
 			req := &Req{
+				app:          app,
+				bodyStream:   bodyStream,
 				LocalAddress: hostName,
 				Method:       method,
 				Path:         path,
@@ -191,11 +346,12 @@ func handleClient(socket net.Conn, app *App) {
 				Queries:      queries,
 				Cookies:      cookies,
 			}
-			res := &Res{
+			res = &Res{
 				Socket:          socket,
 				StatusCode:      200,
-				Headers:         make(map[string][]string),
+				Headers:         make(Header),
 				PrettyPrintJSON: app.PrettyPrintJSON,
+				PrettyPrintXML:  app.PrettyPrintXML,
 			}
 
 			ctx := &Ctx{
@@ -206,9 +362,23 @@ func handleClient(socket net.Conn, app *App) {
 			req.Ctx = ctx
 			res.Ctx = ctx
 
-			handler(req, res)
+			// Release the request resources once the handler returns, even if it panics
+			func() {
+				defer req.cleanup()
+				route.handler(req, res)
+			}()
+
+			// Write the buffered response, unless it was already flushed or streamed,
+			// and end the body of a stream the handler left open
+			res.Flush()
+			res.closeOpenBody()
 		} else {
-			sendResponse(socket, []byte("Not Found"), 404, "text/plain", nil)
+			sendError(socket, app, 404, nil)
+		}
+
+		// Drain whatever wasn't read from the body stream to keep the connection usable
+		if bodyStream != nil && !drainBody(bodyStream) {
+			return
 		}
 
 		// Check if client requested connection close
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strings"
+	"testing"
 	"time"
 )
 
@@ -12,6 +14,8 @@ type MockConn struct {
 	outBuf     []byte
 	inBuf      []byte
 	readOffset int
+	// Number of times the read deadline was set
+	readDeadlines int
 }
 
 // All these functions are mocked to follow the net.Conn interface specifications
@@ -49,6 +53,7 @@ func (m *MockConn) SetDeadline(t time.Time) error {
 }
 
 func (m *MockConn) SetReadDeadline(t time.Time) error {
+	m.readDeadlines++
 	return nil
 }
 
@@ -56,14 +61,149 @@ func (m *MockConn) SetWriteDeadline(t time.Time) error {
 	return nil
 }
 
-// Helper function to mock a request and return the response
-func mockRequest(app *App, method, path, body string) string {
+// Helper function to mock a request with optional extra headers and return the response
+func mockRequest(app *App, method, path, body string, headers map[string]string) string {
 	conn := &MockConn{}
-	req := fmt.Sprintf("%s %s HTTP/1.1\r\nContent-Length: %d\r\n\r\n%s", method, path, len(body), body)
-	conn.inBuf = []byte(req)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s HTTP/1.1\r\nContent-Length: %d\r\n", method, path, len(body))
+	for k, v := range headers {
+		fmt.Fprintf(&sb, "%s: %s\r\n", k, v)
+	}
+	sb.WriteString("\r\n" + body)
+	conn.inBuf = []byte(sb.String())
 
 	// Call handleClient with the mocked connection
 	handleClient(conn, app)
 
 	return string(conn.outBuf)
 }
+
+// Test rejecting large bodies and headers before reading them
+func TestRequestLimits(t *testing.T) {
+	app := NewApp()
+	app.BodyLimit = 10
+	app.MaxHeaderCount = 5
+
+	app.Post("/small", func(req *Req, res *Res) {
+		res.Send("small: " + req.Body)
+	})
+
+	app.Post("/large", func(req *Req, res *Res) {
+		res.Send("large: " + req.Body)
+	}).BodyLimit(100)
+
+	app.Post("/unlimited", func(req *Req, res *Res) {
+		res.Send("unlimited")
+	}).BodyLimit(-1)
+
+	tests := []struct {
+		name     string
+		request  string
+		expected string
+	}{
+		{
+			name:     "Body within the app limit",
+			request:  "POST /small HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello",
+			expected: "small: hello",
+		},
+		{
+			name:     "Body exceeds the app limit",
+			request:  "POST /small HTTP/1.1\r\nContent-Length: 50\r\n\r\n" + strings.Repeat("a", 50),
+			expected: "HTTP/1.1 413 ",
+		},
+		{
+			name:     "Body within the route limit",
+			request:  "POST /large HTTP/1.1\r\nContent-Length: 50\r\n\r\n" + strings.Repeat("a", 50),
+			expected: "large: " + strings.Repeat("a", 50),
+		},
+		{
+			name:     "Body exceeds the route limit",
+			request:  "POST /large HTTP/1.1\r\nContent-Length: 500\r\n\r\n" + strings.Repeat("a", 500),
+			expected: "HTTP/1.1 413 ",
+		},
+		{
+			name:     "Huge declared body is rejected without reading it",
+			request:  "POST /small HTTP/1.1\r\nContent-Length: 9223372036854775807\r\n\r\n",
+			expected: "HTTP/1.1 413 ",
+		},
+		{
+			name:     "Route without a limit",
+			request:  "POST /unlimited HTTP/1.1\r\nContent-Length: 50\r\n\r\n" + strings.Repeat("a", 50),
+			expected: "unlimited",
+		},
+		{
+			name:     "Invalid content length",
+			request:  "POST /small HTTP/1.1\r\nContent-Length: abc\r\n\r\n",
+			expected: "HTTP/1.1 400 Bad Request",
+		},
+		{
+			name:     "Too many headers",
+			request:  "GET /small HTTP/1.1\r\n" + strings.Repeat("Header: value\r\n", 6) + "\r\n",
+			expected: "HTTP/1.1 431 Request Header Fields Too Large",
+		},
+		{
+			name:     "Too long header line",
+			request:  "GET /small HTTP/1.1\r\nHeader: " + strings.Repeat("a", DefaultMaxHeaderLineLength) + "\r\n\r\n",
+			expected: "HTTP/1.1 431 Request Header Fields Too Large",
+		},
+		{
+			name:     "Too long request line",
+			request:  "GET /small?q=" + strings.Repeat("a", DefaultMaxHeaderLineLength) + " HTTP/1.1\r\n\r\n",
+			expected: "HTTP/1.1 414 Request URI Too Long",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &MockConn{}
+			conn.inBuf = []byte(tt.request)
+
+			handleClient(conn, app)
+			output := string(conn.outBuf)
+
+			if !strings.Contains(output, tt.expected) {
+				t.Errorf("Expected response to contain %q, got: %s", tt.expected, output)
+			}
+		})
+	}
+}
+
+// Test recovering from handler panics before and after the response is committed
+func TestHandlerPanic(t *testing.T) {
+	app := NewApp()
+
+	app.Get("/buffered", func(req *Req, res *Res) {
+		res.Send("partial")
+		panic("boom")
+	})
+
+	app.Get("/committed", func(req *Req, res *Res) {
+		res.Send("partial")
+		res.Flush()
+		panic("boom")
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"Panic before committing", "/buffered", "HTTP/1.1 500 "},
+		{"Panic after committing", "/committed", "HTTP/1.1 200 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := mockRequest(app, "GET", tt.path, "", nil)
+
+			if !strings.HasPrefix(output, tt.expected) {
+				t.Errorf("Expected response to start with %q, got: %s", tt.expected, output)
+			}
+
+			if strings.Count(output, "HTTP/1.1 ") != 1 {
+				t.Errorf("Expected a single response, got: %s", output)
+			}
+		})
+	}
+}
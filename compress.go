@@ -0,0 +1,165 @@
+package zttp
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+)
+
+// Min size of the response bodies compressed by the compression middleware, unless configured otherwise (1 KB)
+const DefaultCompressMinSize = 1 << 10
+
+// Options of the compression middleware
+type CompressOptions struct {
+	// Compression level from 1 (fastest) to 9 (smallest), the default level if zero
+	// Levels out of this range are clamped to it
+	Level int
+	// Min body size in bytes to compress, smaller bodies are sent as is, DefaultCompressMinSize if zero
+	MinSize int
+}
+
+// The compression negotiated for a response
+type compression struct {
+	encoding string
+	level    int
+	minSize  int64
+}
+
+// Compress the response bodies with gzip or deflate, whichever the client prefers
+// Small bodies and already compressed content types like images are sent as is
+// Example: app.Use(zttp.Compress())
+func Compress(opts ...CompressOptions) Middleware {
+	var opt CompressOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	level := opt.Level
+	switch {
+	case level == 0, level == flate.DefaultCompression:
+		level = flate.DefaultCompression
+	case level < flate.BestSpeed:
+		level = flate.BestSpeed
+	case level > flate.BestCompression:
+		level = flate.BestCompression
+	}
+
+	minSize := int64(opt.MinSize)
+	if minSize == 0 {
+		minSize = DefaultCompressMinSize
+	}
+
+	return func(req *Req, res *Res, next func()) {
+		// Caches must know that the response depends on the encodings the client accepts
+		res.Vary("Accept-Encoding")
+
+		// Clients that don't send `Accept-Encoding` get the body as is
+		if req.Header("Accept-Encoding") != "" {
+			switch encoding := req.AcceptsEncodings("gzip", "deflate"); encoding {
+			case "gzip", "deflate":
+				res.compression = &compression{encoding: encoding, level: level, minSize: minSize}
+			}
+		}
+
+		next()
+	}
+}
+
+// Return a writer compressing into the passed one
+// Note that `deflate` in HTTP means the zlib format, not raw deflate
+func (c *compression) newWriter(w io.Writer) (io.WriteCloser, error) {
+	if c.encoding == "gzip" {
+		return gzip.NewWriterLevel(w, c.level)
+	}
+
+	return zlib.NewWriterLevel(w, c.level)
+}
+
+// Compress the whole body at once
+func (c *compression) compress(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w, err := c.newWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Check if the response body of the passed size should be compressed
+// A negative size means the size is unknown
+func (res *Res) shouldCompress(size int64) bool {
+	if res.compression == nil || (size >= 0 && size < res.compression.minSize) {
+		return false
+	}
+
+	// Bodiless and partial responses are never compressed
+	switch {
+	case res.StatusCode < 200, res.StatusCode == 204, res.StatusCode == 206, res.StatusCode == 304:
+		return false
+	}
+
+	// The body is already encoded, like precompressed static files
+	if res.Headers.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	return isCompressible(res.ContentType)
+}
+
+// Set the headers of a body compressed with the negotiated encoding
+func (res *Res) markCompressed() {
+	res.Header("Content-Encoding", res.compression.encoding)
+
+	if etag := res.Headers.Get("ETag"); etag != "" {
+		res.Headers.Set("ETag", encodedETag(etag, res.compression.encoding))
+	}
+}
+
+// Return the ETag of the passed encoding of the content with the passed ETag
+// Every encoding is a different representation, so strong ETags must differ between them
+func encodedETag(etag, encoding string) string {
+	if strings.HasPrefix(etag, "W/") || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// Check if compressing the content type would make the body smaller
+// Images, audio, video, archives, and fonts are already compressed
+func isCompressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	if mediaType == "image/svg+xml" {
+		return true
+	}
+
+	for _, prefix := range []string{"image/", "audio/", "video/"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+
+	switch mediaType {
+	case "application/zip", "application/gzip", "application/x-gzip", "application/x-bzip2",
+		"application/x-xz", "application/zstd", "application/x-7z-compressed",
+		"application/x-rar-compressed", "application/pdf", "font/woff", "font/woff2":
+		return false
+	}
+
+	return true
+}
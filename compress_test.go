@@ -0,0 +1,242 @@
+package zttp
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Helper to parse a raw response and decode its body
+func parseCompressedResponse(t *testing.T, raw string) (*http.Response, string) {
+	resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(raw)), nil)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v\n%s", err, raw)
+	}
+
+	defer resp.Body.Close()
+
+	var body io.Reader = resp.Body
+	switch resp.Header.Get("Content-Encoding") {
+	case "gzip":
+		body, err = gzip.NewReader(resp.Body)
+	case "deflate":
+		body, err = zlib.NewReader(resp.Body)
+	}
+
+	if err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+
+	return resp, string(content)
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("compress me please ", 200)
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "large.txt")
+	os.WriteFile(filePath, []byte(large), DefaultFilePerm)
+
+	root := createStaticTree(t, map[string]string{
+		"app.js":    large,
+		"app.js.gz": "precompressed",
+	})
+
+	app := NewApp()
+	app.Use(Compress())
+
+	app.Get("/text", func(req *Req, res *Res) {
+		res.Send(large)
+	})
+	app.Get("/small", func(req *Req, res *Res) {
+		res.Send("small")
+	})
+	app.Get("/json", func(req *Req, res *Res) {
+		res.Json(map[string]string{"data": large})
+	})
+	app.Get("/image", func(req *Req, res *Res) {
+		res.Type("png").Send(large)
+	})
+	app.Get("/file", func(req *Req, res *Res) {
+		res.SendFile(filePath)
+	})
+	app.Static("/static", root, StaticOptions{Precompressed: true})
+
+	tests := []struct {
+		name           string
+		path           string
+		headers        map[string]string
+		encoding       string
+		chunked        bool
+		expectedBody   string
+		expectedStatus int
+	}{
+		{
+			name:           "Gzip",
+			path:           "/text",
+			headers:        map[string]string{"Accept-Encoding": "gzip, deflate"},
+			encoding:       "gzip",
+			expectedBody:   large,
+			expectedStatus: 200,
+		},
+		{
+			name:           "Deflate preferred by quality",
+			path:           "/text",
+			headers:        map[string]string{"Accept-Encoding": "gzip;q=0.5, deflate"},
+			encoding:       "deflate",
+			expectedBody:   large,
+			expectedStatus: 200,
+		},
+		{
+			name:           "Unsupported encoding",
+			path:           "/text",
+			headers:        map[string]string{"Accept-Encoding": "br"},
+			expectedBody:   large,
+			expectedStatus: 200,
+		},
+		{
+			name:           "No Accept-Encoding",
+			path:           "/text",
+			expectedBody:   large,
+			expectedStatus: 200,
+		},
+		{
+			name:           "Small body",
+			path:           "/small",
+			headers:        map[string]string{"Accept-Encoding": "gzip"},
+			expectedBody:   "small",
+			expectedStatus: 200,
+		},
+		{
+			name:           "JSON",
+			path:           "/json",
+			headers:        map[string]string{"Accept-Encoding": "gzip"},
+			encoding:       "gzip",
+			expectedBody:   `{"data":"` + large + `"}`,
+			expectedStatus: 200,
+		},
+		{
+			name:           "Already compressed content type",
+			path:           "/image",
+			headers:        map[string]string{"Accept-Encoding": "gzip"},
+			expectedBody:   large,
+			expectedStatus: 200,
+		},
+		{
+			name:           "Streamed file",
+			path:           "/file",
+			headers:        map[string]string{"Accept-Encoding": "gzip"},
+			encoding:       "gzip",
+			chunked:        true,
+			expectedBody:   large,
+			expectedStatus: 200,
+		},
+		{
+			name:           "Range request",
+			path:           "/file",
+			headers:        map[string]string{"Accept-Encoding": "gzip", "Range": "bytes=0-7"},
+			expectedBody:   "compress",
+			expectedStatus: 206,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := parseCompressedResponse(t, mockRequest(app, "GET", tt.path, "", tt.headers))
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			if got := resp.Header.Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("Expected Content-Encoding %q, got %q", tt.encoding, got)
+			}
+
+			if got := resp.Header.Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Expected Vary: Accept-Encoding, got %q", got)
+			}
+
+			if chunked := len(resp.TransferEncoding) > 0; chunked != tt.chunked {
+				t.Errorf("Expected chunked %v, got %v", tt.chunked, chunked)
+			}
+
+			if body != tt.expectedBody {
+				t.Errorf("Expected body of length %d, got %q", len(tt.expectedBody), body)
+			}
+		})
+	}
+
+	// The precompressed file is not valid gzip, so compressing it again would be caught here
+	raw := mockRequest(app, "GET", "/static/app.js", "", map[string]string{"Accept-Encoding": "gzip"})
+	if !strings.HasSuffix(raw, "\r\n\r\nprecompressed") || strings.Count(raw, "Content-Encoding") != 1 {
+		t.Errorf("Expected the precompressed file to be sent as is, got: %s", raw)
+	}
+}
+
+func TestCompressLevel(t *testing.T) {
+	large := strings.Repeat("compress me please ", 200)
+
+	for _, level := range []int{-5, -1, 1, 9, 10, 100} {
+		t.Run(fmt.Sprint(level), func(t *testing.T) {
+			app := NewApp()
+			app.Use(Compress(CompressOptions{Level: level}))
+
+			app.Get("/text", func(req *Req, res *Res) {
+				res.Send(large)
+			})
+			app.Get("/stream", func(req *Req, res *Res) {
+				w, _ := res.NDJSON()
+				w.Write(large)
+				w.Close()
+			})
+
+			for _, path := range []string{"/text", "/stream"} {
+				resp, body := parseCompressedResponse(t, mockRequest(app, "GET", path, "", map[string]string{"Accept-Encoding": "gzip"}))
+
+				if resp.Header.Get("Content-Encoding") != "gzip" {
+					t.Errorf("Expected %s to be gzipped, got %q", path, resp.Header.Get("Content-Encoding"))
+				}
+
+				if !strings.Contains(body, "compress me please") {
+					t.Errorf("Expected the body of %s, got %q", path, body)
+				}
+			}
+		})
+	}
+}
+
+func TestIsCompressible(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"text/plain; charset=utf-8", true},
+		{"application/json", true},
+		{"image/svg+xml", true},
+		{"image/png", false},
+		{"video/mp4", false},
+		{"application/zip", false},
+		{"Application/GZIP", false},
+		{"font/woff2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			if got := isCompressible(tt.contentType); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
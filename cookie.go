@@ -0,0 +1,207 @@
+package zttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returned by `cookie.Valid()` for cookies that clients would reject or misread
+var ErrInvalidCookie = errors.New("invalid cookie")
+
+type Cookie struct {
+	Name    string    `json:"name"`
+	Value   string    `json:"value"`
+	Path    string    `json:"path"`
+	Domain  string    `json:"domain"`
+	Expires time.Time `json:"expires"`
+	// Zero means no `Max-Age` attribute, and a negative value deletes the cookie with `Max-Age=0`
+	MaxAge   int    `json:"max_age"`
+	Secure   bool   `json:"secure"`
+	HttpOnly bool   `json:"http_only"`
+	SameSite string `json:"same_site"`
+	// Drop `Expires` and `Max-Age`, so the cookie is deleted when the browser session ends
+	SessionOnly bool `json:"session_only"`
+	// Store the cookie separately for every top-level site it's embedded in, requires `Secure`
+	Partitioned bool `json:"partitioned"`
+	// The eviction priority of the cookie, "Low", "Medium", or "High"
+	Priority string `json:"priority"`
+}
+
+// Check the cookie against the rules of RFC 6265bis, like clients do before storing it
+// The value is never invalid, as `String()` percent-encodes the characters a cookie value can't have
+func (cookie Cookie) Valid() error {
+	if !isValidHeaderKey(cookie.Name) {
+		return fmt.Errorf("%w: name %q isn't a valid token", ErrInvalidCookie, cookie.Name)
+	}
+
+	if !isValidCookieAttribute(cookie.Path) {
+		return fmt.Errorf("%w: invalid path %q", ErrInvalidCookie, cookie.Path)
+	}
+
+	if !isValidCookieAttribute(cookie.Domain) || strings.ContainsAny(cookie.Domain, " ,") {
+		return fmt.Errorf("%w: invalid domain %q", ErrInvalidCookie, cookie.Domain)
+	}
+
+	switch cookie.SameSite {
+	case "", "Strict", "Lax":
+	case "None":
+		if !cookie.Secure {
+			return fmt.Errorf("%w: SameSite=None requires Secure", ErrInvalidCookie)
+		}
+	default:
+		return fmt.Errorf("%w: invalid SameSite value %q", ErrInvalidCookie, cookie.SameSite)
+	}
+
+	if cookie.Partitioned && !cookie.Secure {
+		return fmt.Errorf("%w: Partitioned requires Secure", ErrInvalidCookie)
+	}
+
+	if cookie.Priority != "" && cookiePriority(cookie.Priority) == "" {
+		return fmt.Errorf("%w: invalid Priority value %q", ErrInvalidCookie, cookie.Priority)
+	}
+
+	// Clients only accept the cookie name prefixes from secure origins, with the attributes they promise
+	if strings.HasPrefix(cookie.Name, "__Secure-") && !cookie.Secure {
+		return fmt.Errorf("%w: the __Secure- prefix requires Secure", ErrInvalidCookie)
+	}
+
+	if strings.HasPrefix(cookie.Name, "__Host-") && (!cookie.Secure || cookie.Path != "/" || cookie.Domain != "") {
+		return fmt.Errorf("%w: the __Host- prefix requires Secure, Path=/, and no Domain", ErrInvalidCookie)
+	}
+
+	return nil
+}
+
+// Return the cookie as the value of a `Set-Cookie` header
+func (cookie Cookie) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(cookie.Name + "=" + encodeCookieValue(cookie.Value))
+
+	if cookie.Path != "" {
+		sb.WriteString("; Path=" + cookie.Path)
+	}
+	if cookie.Domain != "" {
+		sb.WriteString("; Domain=" + strings.TrimPrefix(cookie.Domain, "."))
+	}
+	if !cookie.SessionOnly {
+		if !cookie.Expires.IsZero() {
+			sb.WriteString("; Expires=" + cookie.Expires.UTC().Format(http.TimeFormat))
+		}
+		if cookie.MaxAge > 0 {
+			sb.WriteString("; Max-Age=" + strconv.Itoa(cookie.MaxAge))
+		} else if cookie.MaxAge < 0 {
+			sb.WriteString("; Max-Age=0")
+		}
+	}
+	if cookie.Secure {
+		sb.WriteString("; Secure")
+	}
+	if cookie.HttpOnly {
+		sb.WriteString("; HttpOnly")
+	}
+	if cookie.SameSite != "" {
+		sb.WriteString("; SameSite=" + cookie.SameSite)
+	}
+	if cookie.Partitioned {
+		sb.WriteString("; Partitioned")
+	}
+	if priority := cookiePriority(cookie.Priority); priority != "" {
+		sb.WriteString("; Priority=" + priority)
+	}
+
+	return sb.String()
+}
+
+// Return the canonical form of the cookie priority, or an empty string if it's not a valid one
+func cookiePriority(priority string) string {
+	for _, p := range []string{"Low", "Medium", "High"} {
+		if strings.EqualFold(priority, p) {
+			return p
+		}
+	}
+
+	return ""
+}
+
+// Check if the attribute value has no control characters or semicolons, which would end it early
+func isValidCookieAttribute(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c < 0x20 || c == 0x7f || c == ';' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Check if the byte can appear in a cookie value as it is
+// Percent signs are encoded too, so decoding the value gives back exactly what was encoded
+func isCookieOctet(c byte) bool {
+	return c >= 0x21 && c <= 0x7e && c != '"' && c != ',' && c != ';' && c != '\\' && c != '%'
+}
+
+// Percent-encode the bytes of the value that can't appear in a cookie value
+// Values like base64 tokens are kept as they are
+func encodeCookieValue(value string) string {
+	for i := 0; i < len(value); i++ {
+		if !isCookieOctet(value[i]) {
+			var sb strings.Builder
+			for j := 0; j < len(value); j++ {
+				if c := value[j]; isCookieOctet(c) {
+					sb.WriteByte(c)
+				} else {
+					fmt.Fprintf(&sb, "%%%02X", c)
+				}
+			}
+			return sb.String()
+		}
+	}
+
+	return value
+}
+
+// Parse the cookies of a `Cookie` request header, as RFC 6265bis describes
+// Values keep everything after the first `=`, lose their surrounding quotes, and are percent-decoded
+// Pairs without a name are skipped, and the first of several cookies with the same name wins,
+// as clients send the cookie with the most specific path first
+func parseCookieHeader(header string) map[string]string {
+	cookies := make(map[string]string)
+
+	for pair := range strings.SplitSeq(header, ";") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+
+		name = strings.TrimSpace(name)
+		if !isValidHeaderKey(name) {
+			continue
+		}
+
+		if _, exists := cookies[name]; exists {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+
+		// Values that only look encoded are kept as they are
+		if strings.Contains(value, "%") {
+			if decoded, err := url.PathUnescape(value); err == nil {
+				value = decoded
+			}
+		}
+
+		cookies[name] = value
+	}
+
+	return cookies
+}
@@ -0,0 +1,56 @@
+package zttp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCookieValid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie Cookie
+		valid  bool
+	}{
+		{"Plain cookie", Cookie{Name: "session", Value: "abc"}, true},
+		{"Empty name", Cookie{Value: "abc"}, false},
+		{"Separator in name", Cookie{Name: "a=b", Value: "abc"}, false},
+		{"Semicolon in path", Cookie{Name: "session", Path: "/; Domain=evil.com"}, false},
+		{"Space in domain", Cookie{Name: "session", Domain: "example .com"}, false},
+		{"Invalid SameSite", Cookie{Name: "session", SameSite: "Loose"}, false},
+		{"SameSite=None without Secure", Cookie{Name: "session", SameSite: "None"}, false},
+		{"SameSite=None with Secure", Cookie{Name: "session", SameSite: "None", Secure: true}, true},
+		{"Partitioned with Secure", Cookie{Name: "embed", Secure: true, Partitioned: true}, true},
+		{"Invalid Priority", Cookie{Name: "session", Priority: "Urgent"}, false},
+		{"Secure prefix without Secure", Cookie{Name: "__Secure-id", Value: "1"}, false},
+		{"Secure prefix", Cookie{Name: "__Secure-id", Value: "1", Secure: true}, true},
+		{"Host prefix with Domain", Cookie{Name: "__Host-id", Path: "/", Domain: "example.com", Secure: true}, false},
+		{"Host prefix", Cookie{Name: "__Host-id", Path: "/", Secure: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cookie.Valid()
+
+			if tt.valid && err != nil {
+				t.Errorf("Expected a valid cookie, got %v", err)
+			}
+
+			if !tt.valid && !errors.Is(err, ErrInvalidCookie) {
+				t.Errorf("Expected ErrInvalidCookie, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCookieValueRoundTrip(t *testing.T) {
+	values := []string{"", "plain", "YWJjZA==", "a b", `{"theme": "dark"}`, "100%", "semi;colon,comma", "naïve"}
+
+	for _, value := range values {
+		t.Run(value, func(t *testing.T) {
+			header := Cookie{Name: "v", Value: value}.String()
+			if got := parseCookieHeader(header)["v"]; got != value {
+				t.Errorf("Expected %q, got %q from %q", value, got, header)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package zttp
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Set the `Content-Disposition` header so the client saves the response as a file instead of showing it
+// The content type is detected from the extension of the filename, unless it's already set
+// Names that aren't plain ASCII are sent both as an ASCII fallback and as UTF-8, as RFC 6266 recommends
+// Example: res.Attachment("report.pdf").Send(report)
+func (res *Res) Attachment(filename ...string) *Res {
+	name := ""
+	if len(filename) > 0 {
+		name = baseFilename(filename[0])
+	}
+
+	res.Headers.Set("Content-Disposition", contentDisposition("attachment", name))
+
+	if name != "" && res.ContentType == "" {
+		res.ContentType = mime.TypeByExtension(filepath.Ext(name))
+	}
+
+	return res
+}
+
+// Send the file at the passed path as an attachment, streamed instead of read into memory
+// The client saves it with the passed filename, or with the name of the file if there's none
+// Like `res.SendFile()`, it answers conditional and range requests, so downloads can be resumed
+// Example: res.Download("./exports/2025.csv", "report.csv")
+func (res *Res) Download(filePath string, filename ...string) {
+	info, err := os.Stat(filePath)
+	if err != nil || info.IsDir() {
+		res.sendError(404, "")
+		return
+	}
+
+	name := filepath.Base(filePath)
+	if len(filename) > 0 && filename[0] != "" {
+		name = filename[0]
+	}
+
+	res.Attachment(name).SendFile(filePath)
+}
+
+// Build a `Content-Disposition` header value of the passed type and filename
+// The `filename` param is a quoted ASCII fallback, and non-ASCII names also get
+// the UTF-8 `filename*` param encoded as RFC 5987 describes, which clients prefer
+func contentDisposition(dispositionType, filename string) string {
+	if filename == "" {
+		return dispositionType
+	}
+
+	fallback := asciiFilename(filename)
+	value := fmt.Sprintf("%s; filename=%s", dispositionType, quoteParam(fallback))
+
+	if fallback != filename {
+		value += "; filename*=UTF-8''" + encodeExtValue(filename)
+	}
+
+	return value
+}
+
+// Return the last element of the filename, so it can't point the client to another directory
+func baseFilename(filename string) string {
+	filename = strings.ReplaceAll(filename, `\`, "/")
+	if i := strings.LastIndex(filename, "/"); i >= 0 {
+		filename = filename[i+1:]
+	}
+
+	return filename
+}
+
+// Replace the characters of the filename that aren't printable ASCII
+func asciiFilename(filename string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '_'
+		}
+		return r
+	}, filename)
+}
+
+// Quote the header param value, escaping the backslashes and quotes in it
+func quoteParam(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// Percent-encode the UTF-8 bytes of the value that aren't `attr-char`s, as RFC 5987 defines them
+func encodeExtValue(value string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if isAttrChar(c) {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+
+	return sb.String()
+}
+
+// Check if the byte can appear in an RFC 5987 value without percent-encoding
+func isAttrChar(c byte) bool {
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+		return true
+	}
+
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
@@ -0,0 +1,142 @@
+package zttp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		filename string
+		expected string
+	}{
+		{"", "attachment"},
+		{"report.pdf", `attachment; filename="report.pdf"`},
+		{"my report.pdf", `attachment; filename="my report.pdf"`},
+		{`say "hi"\.txt`, `attachment; filename="say \"hi\"\\.txt"`},
+		{"résumé.pdf", `attachment; filename="r_sum_.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`},
+		{"€ rates.csv", `attachment; filename="_ rates.csv"; filename*=UTF-8''%E2%82%AC%20rates.csv`},
+		{"日本.txt", `attachment; filename="__.txt"; filename*=UTF-8''%E6%97%A5%E6%9C%AC.txt`},
+		{"line\r\nbreak.txt", `attachment; filename="line__break.txt"; filename*=UTF-8''line%0D%0Abreak.txt`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			if got := contentDisposition("attachment", tt.filename); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	tests := []struct {
+		name                string
+		setup               func(*Res)
+		expectedDisposition string
+		expectedType        string
+	}{
+		{
+			name:                "Without filename",
+			setup:               func(r *Res) { r.Attachment().Send("data") },
+			expectedDisposition: "attachment",
+			expectedType:        "text/plain; charset=utf-8",
+		},
+		{
+			name:                "Type from the extension",
+			setup:               func(r *Res) { r.Attachment("logo.png").Send("data") },
+			expectedDisposition: `attachment; filename="logo.png"`,
+			expectedType:        "image/png",
+		},
+		{
+			name:                "Type already set",
+			setup:               func(r *Res) { r.Type("text/csv").Attachment("logo.png").Send("data") },
+			expectedDisposition: `attachment; filename="logo.png"`,
+			expectedType:        "text/csv",
+		},
+		{
+			name:                "Directories are stripped",
+			setup:               func(r *Res) { r.Attachment(`../../etc\passwd`).Send("data") },
+			expectedDisposition: `attachment; filename="passwd"`,
+			expectedType:        "text/plain; charset=utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &MockConn{}
+			res := &Res{
+				Socket:     conn,
+				StatusCode: 200,
+				Headers:    make(Header),
+			}
+
+			tt.setup(res)
+			res.Flush()
+			response := string(conn.outBuf)
+
+			if disposition := responseHeader(response, "Content-Disposition"); disposition != tt.expectedDisposition {
+				t.Errorf("Expected Content-Disposition %q, got %q", tt.expectedDisposition, disposition)
+			}
+
+			if contentType := responseHeader(response, "Content-Type"); contentType != tt.expectedType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.expectedType, contentType)
+			}
+		})
+	}
+}
+
+func TestDownload(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "export-2025.bin")
+	os.WriteFile(filePath, []byte("0123456789"), DefaultFilePerm)
+
+	app := NewApp()
+	app.Get("/download", func(req *Req, res *Res) {
+		res.Download(filePath)
+	})
+	app.Get("/renamed", func(req *Req, res *Res) {
+		res.Download(filePath, "données.bin")
+	})
+	app.Get("/missing", func(req *Req, res *Res) {
+		res.Download(filepath.Join(dir, "missing.bin"))
+	})
+	app.Get("/directory", func(req *Req, res *Res) {
+		res.Download(dir)
+	})
+
+	tests := []struct {
+		name                string
+		path                string
+		headers             map[string]string
+		expectedStatus      string
+		expectedDisposition string
+		expectedBody        string
+	}{
+		{"File name", "/download", nil, "HTTP/1.1 200 OK", `attachment; filename="export-2025.bin"`, "0123456789"},
+		{"Custom name", "/renamed", nil, "HTTP/1.1 200 OK", `attachment; filename="donn_es.bin"; filename*=UTF-8''donn%C3%A9es.bin`, "0123456789"},
+		{"Resumed download", "/download", map[string]string{"Range": "bytes=6-"}, "HTTP/1.1 206 Partial Content", `attachment; filename="export-2025.bin"`, "6789"},
+		{"Missing file", "/missing", nil, "HTTP/1.1 404 Not Found", "", "Not Found"},
+		{"Directory", "/directory", nil, "HTTP/1.1 404 Not Found", "", "Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := mockRequest(app, "GET", tt.path, "", tt.headers)
+
+			if !strings.HasPrefix(response, tt.expectedStatus) {
+				t.Errorf("Expected status %q, got: %s", tt.expectedStatus, response)
+			}
+
+			if disposition := responseHeader(response, "Content-Disposition"); disposition != tt.expectedDisposition {
+				t.Errorf("Expected Content-Disposition %q, got %q", tt.expectedDisposition, disposition)
+			}
+
+			if _, body, _ := strings.Cut(response, "\r\n\r\n"); body != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package zttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Options of the ETag middleware
+type ETagOptions struct {
+	// Generate weak ETags, for bodies that may differ byte for byte while meaning the same
+	// Note that weak ETags never satisfy `If-Match`, as it requires a strong comparison
+	Weak bool
+	// Return the current validators of the resource an unsafe request like PUT targets,
+	// and whether it exists, to evaluate its `If-Match`, `If-Unmodified-Since`, and `If-None-Match` preconditions
+	// Without it, the preconditions are left to the handler, see `req.CheckPreconditions()`
+	Current func(req *Req) (etag string, lastModified time.Time, exists bool)
+}
+
+// The ETag generation configured for a response
+type etagConfig struct {
+	weak bool
+}
+
+// Generate ETags for the buffered response bodies and answer conditional requests
+// Conditional GET requests get 304 Not Modified if the client's copy is still current,
+// and unsafe requests like PUT get 412 Precondition Failed if their preconditions fail
+// against the current version of the resource returned by `ETagOptions.Current`
+// Example: app.Use(zttp.ETag())
+func ETag(opts ...ETagOptions) Middleware {
+	var opt ETagOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	config := &etagConfig{weak: opt.Weak}
+
+	return func(req *Req, res *Res, next func()) {
+		res.etag = config
+
+		if isUnsafeMethod(req.Method) && opt.Current != nil && req.hasPreconditions() {
+			etag, lastModified, exists := opt.Current(req)
+			if !req.preconditionsMet(etag, lastModified, exists) {
+				res.sendError(412, "")
+				return
+			}
+		}
+
+		next()
+	}
+}
+
+// Set the ETag of the buffered body and check if the client already has this version of it
+func (res *Res) conditionalGet(body []byte) bool {
+	if res.StatusCode != 200 {
+		return false
+	}
+
+	etag := res.Headers.Get("ETag")
+	if etag == "" {
+		sum := sha256.Sum256(body)
+		etag = hashETag(sum[:], res.etag.weak)
+		res.Header("ETag", etag)
+	}
+
+	if res.Ctx == nil || res.Ctx.Req == nil || isUnsafeMethod(res.Ctx.Req.Method) {
+		return false
+	}
+
+	lastModified, _ := http.ParseTime(res.Headers.Get("Last-Modified"))
+
+	return res.notModified(etag, lastModified)
+}
+
+// Check the `If-Match`, `If-Unmodified-Since`, and `If-None-Match` preconditions of the request
+// against the current validators of the resource, where an empty ETag and zero time mean it doesn't exist
+// Handlers of unsafe requests answer 412 Precondition Failed if this returns false:
+//
+//	if !req.CheckPreconditions(doc.ETag, doc.UpdatedAt) {
+//		res.Status(412).Send("Precondition Failed")
+//		return
+//	}
+func (req *Req) CheckPreconditions(etag string, lastModified time.Time) bool {
+	return req.preconditionsMet(etag, lastModified, etag != "" || !lastModified.IsZero())
+}
+
+// Check if the request has any precondition headers
+func (req *Req) hasPreconditions() bool {
+	return req.Header("If-Match") != "" || req.Header("If-Unmodified-Since") != "" || req.Header("If-None-Match") != ""
+}
+
+// Evaluate the preconditions of the request against the current version of the resource, as RFC 9110 orders them
+func (req *Req) preconditionsMet(etag string, lastModified time.Time, exists bool) bool {
+	ifMatch := req.Header("If-Match")
+	ifUnmodifiedSince := req.Header("If-Unmodified-Since")
+	ifNoneMatch := req.Header("If-None-Match")
+
+	if ifMatch != "" {
+		if !matchesIfMatch(ifMatch, exists, etag) {
+			return false
+		}
+	} else if ifUnmodifiedSince != "" && exists && !lastModified.IsZero() {
+		// Resources without a modification time can't be evaluated, so the header is ignored
+		t, err := http.ParseTime(ifUnmodifiedSince)
+		if err == nil && lastModified.Truncate(time.Second).After(t) {
+			return false
+		}
+	}
+
+	if ifNoneMatch != "" && exists {
+		if ifNoneMatch == "*" || (etag != "" && !isEtagStale(etag, []byte(ifNoneMatch))) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Check the `If-Match` header against the current ETag of the resource
+// Unlike `If-None-Match`, the ETags must match strongly, so weak ETags never match
+func matchesIfMatch(ifMatch string, exists bool, etag string) bool {
+	if !exists {
+		return false
+	}
+
+	if strings.TrimSpace(ifMatch) == "*" {
+		return true
+	}
+
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	for tag := range strings.SplitSeq(ifMatch, ",") {
+		if strings.TrimSpace(tag) == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check if the method may change the state of the server
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		return false
+	}
+
+	return true
+}
+
+// Format the ETag of the passed content hash
+func hashETag(sum []byte, weak bool) string {
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+
+	return etag
+}
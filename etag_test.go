@@ -0,0 +1,274 @@
+package zttp
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper to extract a header value from a raw response
+func responseHeader(response, key string) string {
+	head, _, _ := strings.Cut(response, "\r\n\r\n")
+	for line := range strings.SplitSeq(head, "\r\n") {
+		if value, ok := strings.CutPrefix(line, key+": "); ok {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func TestETagConditionalGet(t *testing.T) {
+	lastModified := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	app := NewApp()
+	app.Use(ETag())
+
+	app.Get("/data", func(req *Req, res *Res) {
+		res.Send("some data")
+	})
+	app.Get("/tagged", func(req *Req, res *Res) {
+		res.Header("ETag", `"v1"`).Send("tagged data")
+	})
+	app.Get("/dated", func(req *Req, res *Res) {
+		res.Header("Last-Modified", lastModified.Format(http.TimeFormat)).Send("dated data")
+	})
+	app.Get("/error", func(req *Req, res *Res) {
+		res.Status(500).Send("failed")
+	})
+
+	response := mockRequest(app, "GET", "/data", "", nil)
+	etag := responseHeader(response, "ETag")
+	if !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("Expected a strong ETag, got %q", etag)
+	}
+
+	if again := responseHeader(mockRequest(app, "GET", "/data", "", nil), "ETag"); again != etag {
+		t.Errorf("Expected a stable ETag %q, got %q", etag, again)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		headers        map[string]string
+		expectedStatus string
+		expectedBody   string
+	}{
+		{
+			name:           "Matching If-None-Match",
+			path:           "/data",
+			headers:        map[string]string{"If-None-Match": etag},
+			expectedStatus: "HTTP/1.1 304 Not Modified",
+		},
+		{
+			name:           "Matching weak If-None-Match",
+			path:           "/data",
+			headers:        map[string]string{"If-None-Match": `"other", W/` + etag},
+			expectedStatus: "HTTP/1.1 304 Not Modified",
+		},
+		{
+			name:           "Stale If-None-Match",
+			path:           "/data",
+			headers:        map[string]string{"If-None-Match": `"other"`},
+			expectedStatus: "HTTP/1.1 200 OK",
+			expectedBody:   "some data",
+		},
+		{
+			name:           "ETag set by the handler",
+			path:           "/tagged",
+			headers:        map[string]string{"If-None-Match": `"v1"`},
+			expectedStatus: "HTTP/1.1 304 Not Modified",
+		},
+		{
+			name:           "If-Modified-Since after modification",
+			path:           "/dated",
+			headers:        map[string]string{"If-Modified-Since": lastModified.Add(time.Hour).Format(http.TimeFormat)},
+			expectedStatus: "HTTP/1.1 304 Not Modified",
+		},
+		{
+			name:           "If-Modified-Since before modification",
+			path:           "/dated",
+			headers:        map[string]string{"If-Modified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)},
+			expectedStatus: "HTTP/1.1 200 OK",
+			expectedBody:   "dated data",
+		},
+		{
+			name:           "Error responses are not conditional",
+			path:           "/error",
+			headers:        map[string]string{"If-None-Match": "*"},
+			expectedStatus: "HTTP/1.1 500 Internal Server Error",
+			expectedBody:   "failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := mockRequest(app, "GET", tt.path, "", tt.headers)
+
+			if !strings.HasPrefix(response, tt.expectedStatus) {
+				t.Errorf("Expected status %q, got: %s", tt.expectedStatus, response)
+			}
+
+			_, body, _ := strings.Cut(response, "\r\n\r\n")
+			if body != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, body)
+			}
+
+			if strings.HasPrefix(response, "HTTP/1.1 304") && strings.Contains(response, "Content-Length") {
+				t.Errorf("Expected no Content-Length for 304, got: %s", response)
+			}
+		})
+	}
+}
+
+func TestETagWeak(t *testing.T) {
+	app := NewApp()
+	app.Use(ETag(ETagOptions{Weak: true}))
+
+	app.Get("/data", func(req *Req, res *Res) {
+		res.Send("some data")
+	})
+
+	etag := responseHeader(mockRequest(app, "GET", "/data", "", nil), "ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("Expected a weak ETag, got %q", etag)
+	}
+
+	response := mockRequest(app, "GET", "/data", "", map[string]string{"If-None-Match": etag})
+	if !strings.HasPrefix(response, "HTTP/1.1 304 Not Modified") {
+		t.Errorf("Expected 304 for a matching weak ETag, got: %s", response)
+	}
+}
+
+func TestETagPreconditions(t *testing.T) {
+	lastModified := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	etag := `"v1"`
+	getRuns := 0
+
+	app := NewApp()
+	app.Use(ETag(ETagOptions{
+		Current: func(req *Req) (string, time.Time, bool) {
+			if req.Path != "/doc" {
+				return "", time.Time{}, false
+			}
+			return etag, lastModified, true
+		},
+	}))
+
+	app.Get("/doc", func(req *Req, res *Res) {
+		getRuns++
+		res.Send("version 1")
+	})
+	app.Put("/doc", func(req *Req, res *Res) {
+		res.Send("updated")
+	})
+	app.Put("/new", func(req *Req, res *Res) {
+		res.Send("created")
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		headers  map[string]string
+		expected string
+	}{
+		{"Unconditional", "/doc", nil, "updated"},
+		{"Matching If-Match", "/doc", map[string]string{"If-Match": `"other", ` + etag}, "updated"},
+		{"Stale If-Match", "/doc", map[string]string{"If-Match": `"other"`}, "HTTP/1.1 412 Precondition Failed"},
+		{"Weak If-Match never matches", "/doc", map[string]string{"If-Match": "W/" + etag}, "HTTP/1.1 412 Precondition Failed"},
+		{"If-Match any on existing resource", "/doc", map[string]string{"If-Match": "*"}, "updated"},
+		{"If-Match any on missing resource", "/new", map[string]string{"If-Match": "*"}, "HTTP/1.1 412 Precondition Failed"},
+		{"If-Unmodified-Since after modification", "/doc", map[string]string{"If-Unmodified-Since": lastModified.Add(time.Hour).Format(http.TimeFormat)}, "updated"},
+		{"If-Unmodified-Since before modification", "/doc", map[string]string{"If-Unmodified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)}, "HTTP/1.1 412 Precondition Failed"},
+		{"If-Match takes precedence over If-Unmodified-Since", "/doc", map[string]string{"If-Match": etag, "If-Unmodified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)}, "updated"},
+		{"If-None-Match any on existing resource", "/doc", map[string]string{"If-None-Match": "*"}, "HTTP/1.1 412 Precondition Failed"},
+		{"If-None-Match any on missing resource", "/new", map[string]string{"If-None-Match": "*"}, "created"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := mockRequest(app, "PUT", tt.path, "", tt.headers)
+			if !strings.Contains(response, tt.expected) {
+				t.Errorf("Expected response to contain %q, got: %s", tt.expected, response)
+			}
+
+			if strings.Contains(tt.expected, "412") && strings.Contains(response, "updated") {
+				t.Errorf("Expected the handler not to run, got: %s", response)
+			}
+		})
+	}
+
+	if getRuns != 0 {
+		t.Errorf("Expected the GET route not to run for preconditions, ran %d times", getRuns)
+	}
+
+	// Without a `Current` callback, the preconditions are left to the handler
+	plain := NewApp()
+	plain.Use(ETag())
+	plain.Put("/doc", func(req *Req, res *Res) {
+		if !req.CheckPreconditions(etag, lastModified) {
+			res.Status(412).Send("stale")
+			return
+		}
+		res.Send("updated")
+	})
+
+	if response := mockRequest(plain, "PUT", "/doc", "", map[string]string{"If-Match": etag}); !strings.HasSuffix(response, "updated") {
+		t.Errorf("Expected the matching update to pass, got: %s", response)
+	}
+	if response := mockRequest(plain, "PUT", "/doc", "", map[string]string{"If-Match": `"v0"`}); !strings.HasPrefix(response, "HTTP/1.1 412") {
+		t.Errorf("Expected the handler to reject the stale update, got: %s", response)
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	lastModified := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		headers      map[string]string
+		etag         string
+		lastModified time.Time
+		expected     bool
+	}{
+		{"No preconditions", nil, `"v1"`, lastModified, true},
+		{"Matching If-Match", map[string]string{"If-Match": `"v1"`}, `"v1"`, lastModified, true},
+		{"If-Match on missing resource", map[string]string{"If-Match": "*"}, "", time.Time{}, false},
+		{"If-None-Match any on missing resource", map[string]string{"If-None-Match": "*"}, "", time.Time{}, true},
+		{"If-Unmodified-Since without ETag", map[string]string{"If-Unmodified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)}, "", lastModified, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Req{Method: "PUT", Headers: tt.headers}
+			if got := req.CheckPreconditions(tt.etag, tt.lastModified); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestETagWithCompression(t *testing.T) {
+	large := strings.Repeat("compress me please ", 200)
+
+	app := NewApp()
+	app.Use(Compress())
+	app.Use(ETag())
+
+	app.Get("/data", func(req *Req, res *Res) {
+		res.Send(large)
+	})
+
+	plain := responseHeader(mockRequest(app, "GET", "/data", "", nil), "ETag")
+	gzipped := responseHeader(mockRequest(app, "GET", "/data", "", map[string]string{"Accept-Encoding": "gzip"}), "ETag")
+
+	if plain == "" || gzipped == "" || plain == gzipped {
+		t.Fatalf("Expected different ETags per encoding, got %q and %q", plain, gzipped)
+	}
+
+	response := mockRequest(app, "GET", "/data", "", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": gzipped})
+	if !strings.HasPrefix(response, "HTTP/1.1 304 Not Modified") {
+		t.Errorf("Expected 304 for the compressed ETag, got: %s", response)
+	}
+}
@@ -4,3 +4,7 @@ curl -i -X GET "localhost:8080/static/home.html"
 echo "\n"
 curl -O "localhost:8080/static/download.png"
 echo "\n"
+curl -i -X GET "localhost:8080/assets/home.html"
+echo "\n"
+curl -i -X GET "localhost:8080/assets/"
+echo "\n"
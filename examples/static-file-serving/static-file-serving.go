@@ -17,5 +17,12 @@ func main() {
 		res.Static("download.png", "./public")
 	})
 
+	// Serve the whole directory tree under /assets
+	app.Static("/assets", "./public", zttp.StaticOptions{
+		Browse: true,
+		MaxAge: 3600,
+		ETag:   true,
+	})
+
 	app.Start(8080)
 }
@@ -1,3 +1,5 @@
 module github.com/muhammadzkralla/zttp
 
 go 1.24.1
+
+require golang.org/x/text v0.28.0
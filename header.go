@@ -0,0 +1,164 @@
+package zttp
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The value of the `Server` response header, unless the handler sets another one
+const serverName = "zttp"
+
+// Header names whose usual casing differs from the canonical MIME casing
+var commonHeaderKeys = map[string]string{
+	"Content-Md5":      "Content-MD5",
+	"Dnt":              "DNT",
+	"Etag":             "ETag",
+	"Te":               "TE",
+	"Www-Authenticate": "WWW-Authenticate",
+	"X-Xss-Protection": "X-XSS-Protection",
+}
+
+// The response headers, keyed by their canonical names like `Content-Type`
+// Values are sanitized when the headers are written, so they can never inject other headers
+type Header map[string][]string
+
+// Set the value of the header key, replacing its current values
+func (h Header) Set(key, value string) {
+	h.Del(key)
+	h[canonicalHeaderKey(key)] = []string{value}
+}
+
+// Add a value to the header key, keeping its current values
+func (h Header) Add(key, value string) {
+	key = canonicalHeaderKey(key)
+	h[key] = append(h[key], value)
+}
+
+// Return the first value of the header key, or an empty string if it's not set
+func (h Header) Get(key string) string {
+	if values := h.Values(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// Return all the values of the header key
+func (h Header) Values(key string) []string {
+	if values, ok := h[canonicalHeaderKey(key)]; ok {
+		return values
+	}
+
+	// Headers set on the map directly may not use the canonical key
+	for k, values := range h {
+		if strings.EqualFold(k, key) {
+			return values
+		}
+	}
+
+	return nil
+}
+
+// Remove all the values of the header key
+func (h Header) Del(key string) {
+	for k := range h {
+		if strings.EqualFold(k, key) {
+			delete(h, k)
+		}
+	}
+}
+
+// Write the headers sorted by their keys, so the output is the same on every response
+// The headers the framework writes itself are skipped
+func (h Header) write(w io.Writer) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		switch canonicalHeaderKey(k) {
+		case "Content-Length", "Content-Type", "Transfer-Encoding":
+			continue
+		}
+
+		// A key that isn't a valid token could break the response apart
+		if !isValidHeaderKey(k) {
+			log.Printf("Warning: Invalid response header key: %q", k)
+			continue
+		}
+
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(w, "%s: %s\r\n", canonicalHeaderKey(k), sanitizeHeaderValue(v))
+		}
+	}
+}
+
+// Return the canonical form of the header key, keeping the usual casing of names like `ETag`
+func canonicalHeaderKey(key string) string {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	if common, ok := commonHeaderKeys[key]; ok {
+		return common
+	}
+
+	return key
+}
+
+// Check if the header key is a valid HTTP token
+func isValidHeaderKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			continue
+		}
+
+		if !strings.ContainsRune("!#$%&'*+-.^_`|~", rune(c)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Replace the line breaks of a header value, so it can't end the header and start another one
+func sanitizeHeaderValue(value string) string {
+	if !strings.ContainsAny(value, "\r\n") {
+		return value
+	}
+
+	return strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(value)
+}
+
+// Add the headers every response should have, unless they're already set
+func (h Header) withDefaults() Header {
+	if h.Get("Date") != "" && h.Get("Server") != "" {
+		return h
+	}
+
+	headers := make(Header, len(h)+2)
+	for k, values := range h {
+		headers[k] = values
+	}
+
+	if headers.Get("Date") == "" {
+		headers.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	if headers.Get("Server") == "" {
+		headers.Set("Server", serverName)
+	}
+
+	return headers
+}
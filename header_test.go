@@ -0,0 +1,127 @@
+package zttp
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHeader(t *testing.T) {
+	h := make(Header)
+
+	h.Add("content-language", "en")
+	h.Add("CONTENT-LANGUAGE", "ar")
+	h.Set("etag", `"v1"`)
+	h["x-raw-key"] = []string{"raw"}
+
+	tests := []struct {
+		key      string
+		expected []string
+	}{
+		{"Content-Language", []string{"en", "ar"}},
+		{"content-language", []string{"en", "ar"}},
+		{"ETag", []string{`"v1"`}},
+		{"X-Raw-Key", []string{"raw"}},
+		{"Missing", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := h.Values(tt.key); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if _, ok := h["ETag"]; !ok {
+		t.Errorf("Expected the canonical ETag key, got %v", h)
+	}
+
+	h.Set("Content-Language", "fr")
+	if got := h.Values("Content-Language"); !reflect.DeepEqual(got, []string{"fr"}) {
+		t.Errorf("Expected Set to replace the values, got %v", got)
+	}
+
+	h.Del("X-RAW-KEY")
+	if h.Get("X-Raw-Key") != "" {
+		t.Errorf("Expected the header to be deleted, got %v", h)
+	}
+}
+
+func TestCanonicalHeaderKey(t *testing.T) {
+	tests := map[string]string{
+		"content-type":     "Content-Type",
+		"X-CUSTOM-HEADER":  "X-Custom-Header",
+		"etag":             "ETag",
+		"www-authenticate": "WWW-Authenticate",
+		"x-xss-protection": "X-XSS-Protection",
+	}
+
+	for key, expected := range tests {
+		if got := canonicalHeaderKey(key); got != expected {
+			t.Errorf("canonicalHeaderKey(%q) = %q, want %q", key, got, expected)
+		}
+	}
+}
+
+func TestWriteHead(t *testing.T) {
+	conn := &MockConn{}
+	headers := Header{}
+	headers.Add("Zeta", "last")
+	headers.Add("Alpha", "first")
+	headers.Add("X-Split", "value\r\nSet-Cookie: injected=true")
+	headers.Add("Content-Type", "text/html")
+	headers["Bad Key"] = []string{"dropped"}
+
+	writeHead(conn, 200, 2, "application/json", headers)
+	output := string(conn.outBuf)
+
+	if strings.Count(output, "Content-Type:") != 1 || !strings.Contains(output, "Content-Type: application/json\r\n") {
+		t.Errorf("Expected a single Content-Type from the passed content type, got: %s", output)
+	}
+
+	if strings.Contains(output, "\r\nSet-Cookie") || !strings.Contains(output, "X-Split: value Set-Cookie: injected=true\r\n") {
+		t.Errorf("Expected the line break of the value to be replaced, got: %s", output)
+	}
+
+	if strings.Contains(output, "Bad Key") {
+		t.Errorf("Expected the invalid key to be dropped, got: %s", output)
+	}
+
+	if !strings.Contains(output, "Server: zttp\r\n") {
+		t.Errorf("Expected a Server header, got: %s", output)
+	}
+
+	date := ""
+	for line := range strings.SplitSeq(output, "\r\n") {
+		if value, ok := strings.CutPrefix(line, "Date: "); ok {
+			date = value
+		}
+	}
+
+	if _, err := http.ParseTime(date); err != nil {
+		t.Errorf("Expected a valid Date header, got %q", date)
+	}
+
+	// The headers are sorted by key
+	alpha := strings.Index(output, "Alpha: first")
+	dateIdx := strings.Index(output, "Date: ")
+	zeta := strings.Index(output, "Zeta: last")
+	if alpha < 0 || alpha > dateIdx || dateIdx > zeta {
+		t.Errorf("Expected the headers in a stable sorted order, got: %s", output)
+	}
+
+	// Headers set by the handler are kept
+	conn = &MockConn{}
+	writeHead(conn, 200, 0, "", Header{"Server": {"custom"}, "Content-Type": {"text/css"}})
+	output = string(conn.outBuf)
+
+	if !strings.Contains(output, "Server: custom\r\n") || strings.Contains(output, "Server: zttp") {
+		t.Errorf("Expected the custom Server header, got: %s", output)
+	}
+
+	if !strings.Contains(output, "Content-Type: text/css\r\n") {
+		t.Errorf("Expected the Content-Type header to be used, got: %s", output)
+	}
+}
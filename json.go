@@ -0,0 +1,217 @@
+package zttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Encodes the JSON responses of the app, set on `app.JSONEncoder`
+type JSONEncoder interface {
+	// Write the JSON encoding of v to w, indented with the passed indent unless it's empty
+	Encode(w io.Writer, v any, indent string) error
+}
+
+// Decodes the JSON request bodies of the app, set on `app.JSONDecoder`
+type JSONDecoder interface {
+	// Decode the JSON value of r into the value v points to
+	Decode(r io.Reader, v any) error
+}
+
+// The default JSON codec of the app, built on `encoding/json`
+// Its options make decoding stricter or more precise, like:
+// app.JSONDecoder = zttp.JSONCodec{DisallowUnknownFields: true}
+type JSONCodec struct {
+	// Fail decoding objects with fields the target struct doesn't have
+	DisallowUnknownFields bool
+	// Decode numbers into `json.Number` instead of `float64`, so large integers keep their precision
+	UseNumber bool
+	// Keep characters like `<` and `&` as they are, instead of escaping them for embedding in HTML
+	DisableHTMLEscape bool
+}
+
+// Write the JSON encoding of v to w, followed by a newline like `json.Encoder` does
+func (codec JSONCodec) Encode(w io.Writer, v any, indent string) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(!codec.DisableHTMLEscape)
+	if indent != "" {
+		encoder.SetIndent("", indent)
+	}
+
+	return encoder.Encode(v)
+}
+
+// Decode the single JSON value of r into the value v points to
+// Anything but whitespace after the value is an error, as it is for `json.Unmarshal`
+func (codec JSONCodec) Decode(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	if codec.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if codec.UseNumber {
+		decoder.UseNumber()
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level JSON value")
+	}
+
+	return nil
+}
+
+// Return the JSON encoder of the app, or the default one
+func (app *App) jsonEncoder() JSONEncoder {
+	if app == nil || app.JSONEncoder == nil {
+		return JSONCodec{}
+	}
+
+	return app.JSONEncoder
+}
+
+// Return the JSON decoder of the app, or the default one
+func (app *App) jsonDecoder() JSONDecoder {
+	if app == nil || app.JSONDecoder == nil {
+		return JSONCodec{}
+	}
+
+	return app.JSONDecoder
+}
+
+// The query param of the JSONP callback name, unless another one is passed to `res.Jsonp()`
+const DefaultJSONPCallbackParam = "callback"
+
+// Callback names are dotted JavaScript identifiers like `jQuery123` or `app.widgets.render`,
+// anything else could inject scripts into the response
+var jsonpCallbackPattern = regexp.MustCompile(`^[A-Za-z_$][\w$]*(\.[A-Za-z_$][\w$]*)*$`)
+
+// Max length of a JSONP callback name
+const maxJSONPCallbackLength = 128
+
+// This function sends a JSONP response, calling the callback named by the query param with the data
+// Without a callback name, it's a plain JSON response
+// Invalid callback names get 400, so they can't inject scripts into the page loading the response
+// Example: res.Jsonp(data) for "/widget?callback=render" sends `render({...});`
+func (res *Res) Jsonp(data any, callbackParam ...string) error {
+	if err := res.checkWritable(); err != nil {
+		return err
+	}
+
+	param := DefaultJSONPCallbackParam
+	if len(callbackParam) > 0 && callbackParam[0] != "" {
+		param = callbackParam[0]
+	}
+
+	callback := ""
+	if req := res.request(); req != nil {
+		callback = strings.TrimSpace(req.Query(param))
+	}
+
+	if callback == "" {
+		return res.Json(data)
+	}
+
+	if len(callback) > maxJSONPCallbackLength || !jsonpCallbackPattern.MatchString(callback) {
+		return res.sendError(400, "Invalid JSONP Callback")
+	}
+
+	var buf bytes.Buffer
+	if err := res.app().jsonEncoder().Encode(&buf, data, res.jsonIndent()); err != nil {
+		log.Println("Error parsing json")
+		return res.sendError(500, "JSON Marshal Failed")
+	}
+
+	// These line terminators are valid in JSON strings but not in older JavaScript ones
+	raw := strings.NewReplacer("\u2028", `\u2028`, "\u2029", `\u2029`).Replace(strings.TrimSuffix(buf.String(), "\n"))
+
+	// The comment stops the body from starting with bytes an attacker controls, like a Flash file signature,
+	// and nosniff stops browsers from running it as anything but JavaScript
+	res.Headers.Set("X-Content-Type-Options", "nosniff")
+	res.ContentType = "text/javascript; charset=utf-8"
+
+	return res.send([]byte("/**/ typeof " + callback + " === 'function' && " + callback + "(" + raw + ");"))
+}
+
+// Writes newline-delimited JSON items to a chunked response, one item per line
+// The response is committed when the writer is created, so errors while writing can only cut the body short
+type NDJSONWriter struct {
+	res  *Res
+	body io.WriteCloser
+}
+
+// Start a newline-delimited JSON response, `application/x-ndjson`, and return its writer
+// Every item is encoded with the app's `JSONEncoder` and sent as soon as it's written,
+// unless the response is compressed, where `Flush()` sends what's written so far
+// The body ends with `Close()`, or once the handler returns if it's still open
+// Example:
+//
+//	w, err := res.NDJSON()
+//	for _, row := range rows {
+//		w.Write(row)
+//	}
+//	w.Close()
+func (res *Res) NDJSON() (*NDJSONWriter, error) {
+	if err := res.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	res.ContentType = "application/x-ndjson"
+
+	body, err := res.chunkedBody()
+	if err != nil {
+		return nil, err
+	}
+
+	res.openBody = body
+
+	return &NDJSONWriter{res: res, body: body}, nil
+}
+
+// Encode the item as a single line of JSON and write it
+func (w *NDJSONWriter) Write(item any) error {
+	if w.body == nil {
+		return ErrResponseSent
+	}
+
+	var buf bytes.Buffer
+	if err := w.res.app().jsonEncoder().Encode(&buf, item, ""); err != nil {
+		return err
+	}
+
+	line := bytes.TrimRight(buf.Bytes(), "\n")
+	if bytes.ContainsAny(line, "\r\n") {
+		return errors.New("encoded NDJSON item spans multiple lines")
+	}
+
+	_, err := w.body.Write(append(line, '\n'))
+	return err
+}
+
+// Send the items written so far, if the compression of the response holds them back
+func (w *NDJSONWriter) Flush() error {
+	if flusher, ok := w.body.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+
+	return nil
+}
+
+// End the body of the response
+func (w *NDJSONWriter) Close() error {
+	if w.body == nil {
+		return nil
+	}
+
+	body := w.body
+	w.body = nil
+	w.res.openBody = nil
+
+	return body.Close()
+}
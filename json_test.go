@@ -0,0 +1,304 @@
+package zttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// A codec wrapping every encoded value, to check the app's codec is used
+type wrappingCodec struct{}
+
+func (wrappingCodec) Encode(w io.Writer, v any, indent string) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, `{"data":`+string(raw)+"}")
+	return err
+}
+
+func TestJSONCodec(t *testing.T) {
+	type payload struct {
+		ID int `json:"id"`
+	}
+
+	tests := []struct {
+		name    string
+		codec   JSONCodec
+		body    string
+		wantErr bool
+	}{
+		{"Known fields", JSONCodec{}, `{"id":1}`, false},
+		{"Unknown fields allowed", JSONCodec{}, `{"id":1,"extra":true}`, false},
+		{"Unknown fields disallowed", JSONCodec{DisallowUnknownFields: true}, `{"id":1,"extra":true}`, true},
+		{"Trailing whitespace", JSONCodec{}, "{\"id\":1}\n ", false},
+		{"Trailing data", JSONCodec{}, `{"id":1}{"id":2}`, true},
+		{"Invalid JSON", JSONCodec{}, `{"id":`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p payload
+			err := tt.codec.Decode(strings.NewReader(tt.body), &p)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	var number map[string]any
+	if err := (JSONCodec{UseNumber: true}).Decode(strings.NewReader(`{"n":9007199254740993}`), &number); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n, ok := number["n"].(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Errorf("Expected the number to keep its precision, got %#v", number["n"])
+	}
+
+	var sb strings.Builder
+	if err := (JSONCodec{DisableHTMLEscape: true}).Encode(&sb, "<b>&</b>", ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if sb.String() != "\"<b>&</b>\"\n" {
+		t.Errorf("Expected the HTML characters as they are, got %q", sb.String())
+	}
+}
+
+func TestAppJSONCodec(t *testing.T) {
+	app := NewApp()
+	app.JSONEncoder = wrappingCodec{}
+	app.JSONDecoder = JSONCodec{DisallowUnknownFields: true}
+
+	app.Post("/order", func(req *Req, res *Res) {
+		var order Order
+		if err := req.ParseJson(&order); err != nil {
+			res.Status(400).Send("invalid order")
+			return
+		}
+
+		res.Json(order)
+	})
+
+	response := mockRequest(app, "POST", "/order", "", nil)
+	if !strings.HasPrefix(response, "HTTP/1.1 400 Bad Request") {
+		t.Errorf("Expected the empty body to be rejected, got: %s", response)
+	}
+
+	post := func(body string) string {
+		conn := &MockConn{}
+		conn.inBuf = fmt.Appendf(nil, "POST /order HTTP/1.1\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+		handleClient(conn, app)
+		return string(conn.outBuf)
+	}
+
+	response = post(`{"id":7,"item":"book","extra":true}`)
+	if !strings.HasPrefix(response, "HTTP/1.1 400 Bad Request") {
+		t.Errorf("Expected the unknown field to be rejected, got: %s", response)
+	}
+
+	response = post(`{"id":7,"item":"book"}`)
+	if _, got, _ := strings.Cut(response, "\r\n\r\n"); got != `{"data":{"id":7,"item":"book"}}` {
+		t.Errorf("Expected the app's encoder to be used, got %q", got)
+	}
+}
+
+func TestJsonStream(t *testing.T) {
+	large := make([]Order, 200)
+	for i := range large {
+		large[i] = Order{ID: i, Item: "book"}
+	}
+
+	app := NewApp()
+	app.Get("/orders", func(req *Req, res *Res) {
+		res.JsonStream(large)
+	})
+
+	compressed := NewApp()
+	compressed.Use(Compress())
+	compressed.Get("/orders", func(req *Req, res *Res) {
+		res.JsonStream(large)
+	})
+
+	tests := []struct {
+		name             string
+		app              *App
+		headers          map[string]string
+		expectedEncoding string
+	}{
+		{"Plain", app, nil, ""},
+		{"Compressed", compressed, map[string]string{"Accept-Encoding": "gzip"}, "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := parseCompressedResponse(t, mockRequest(tt.app, "GET", "/orders", "", tt.headers))
+
+			if len(resp.TransferEncoding) != 1 || resp.TransferEncoding[0] != "chunked" {
+				t.Errorf("Expected a chunked response, got %v", resp.TransferEncoding)
+			}
+
+			if resp.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Expected application/json, got %q", resp.Header.Get("Content-Type"))
+			}
+
+			if resp.Header.Get("Content-Encoding") != tt.expectedEncoding {
+				t.Errorf("Expected Content-Encoding %q, got %q", tt.expectedEncoding, resp.Header.Get("Content-Encoding"))
+			}
+
+			var orders []Order
+			if err := json.Unmarshal([]byte(body), &orders); err != nil {
+				t.Fatalf("Failed to decode the body: %v", err)
+			}
+
+			if len(orders) != len(large) || orders[199] != large[199] {
+				t.Errorf("Expected %d orders, got %d", len(large), len(orders))
+			}
+		})
+	}
+}
+
+func TestJsonStreamEncodingError(t *testing.T) {
+	app := NewApp()
+	app.Get("/broken", func(req *Req, res *Res) {
+		if err := res.JsonStream(make(chan int)); err == nil {
+			t.Error("Expected an encoding error")
+		}
+	})
+
+	response := mockRequest(app, "GET", "/broken", "", nil)
+
+	if !strings.Contains(response, "Transfer-Encoding: chunked") || !strings.HasSuffix(response, "0\r\n\r\n") {
+		t.Errorf("Expected the chunked body to be ended, got: %q", response)
+	}
+}
+
+func TestJsonp(t *testing.T) {
+	app := NewApp()
+	app.Get("/widget", func(req *Req, res *Res) {
+		res.Jsonp(map[string]string{"text": "a\u2028b"})
+	})
+	app.Get("/custom", func(req *Req, res *Res) {
+		res.Jsonp(Order{ID: 7}, "cb")
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus string
+		expectedType   string
+		expectedBody   string
+	}{
+		{"Callback", "/widget?callback=render", "HTTP/1.1 200 OK", "text/javascript; charset=utf-8", `/**/ typeof render === 'function' && render({"text":"a\u2028b"});`},
+		{"Dotted callback", "/widget?callback=app.widgets.render_1", "HTTP/1.1 200 OK", "text/javascript; charset=utf-8", `/**/ typeof app.widgets.render_1 === 'function' && app.widgets.render_1({"text":"a\u2028b"});`},
+		{"Custom param", "/custom?cb=$jq", "HTTP/1.1 200 OK", "text/javascript; charset=utf-8", `/**/ typeof $jq === 'function' && $jq({"id":7,"item":""});`},
+		{"No callback", "/widget", "HTTP/1.1 200 OK", "application/json", `{"text":"a\u2028b"}`},
+		{"Script injection", "/widget?callback=alert(1);x", "HTTP/1.1 400 Bad Request", "text/plain; charset=utf-8", "Bad Request: Invalid JSONP Callback"},
+		{"Markup injection", "/widget?callback=%3Cscript%3E", "HTTP/1.1 400 Bad Request", "text/plain; charset=utf-8", "Bad Request: Invalid JSONP Callback"},
+		{"Leading digit", "/widget?callback=1cb", "HTTP/1.1 400 Bad Request", "text/plain; charset=utf-8", "Bad Request: Invalid JSONP Callback"},
+		{"Empty segment", "/widget?callback=a..b", "HTTP/1.1 400 Bad Request", "text/plain; charset=utf-8", "Bad Request: Invalid JSONP Callback"},
+		{"Too long", "/widget?callback=" + strings.Repeat("a", 129), "HTTP/1.1 400 Bad Request", "text/plain; charset=utf-8", "Bad Request: Invalid JSONP Callback"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := mockRequest(app, "GET", tt.path, "", nil)
+
+			if !strings.HasPrefix(response, tt.expectedStatus) {
+				t.Errorf("Expected status %q, got: %s", tt.expectedStatus, response)
+			}
+
+			if contentType := responseHeader(response, "Content-Type"); contentType != tt.expectedType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.expectedType, contentType)
+			}
+
+			if _, body, _ := strings.Cut(response, "\r\n\r\n"); body != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, body)
+			}
+		})
+	}
+
+	response := mockRequest(app, "GET", "/widget?callback=render", "", nil)
+	if responseHeader(response, "X-Content-Type-Options") != "nosniff" {
+		t.Errorf("Expected X-Content-Type-Options: nosniff, got: %s", response)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	orders := []Order{{ID: 1, Item: "book"}, {ID: 2, Item: "pen"}, {ID: 3, Item: "line\nbreak"}}
+	expected := `{"id":1,"item":"book"}` + "\n" + `{"id":2,"item":"pen"}` + "\n" + `{"id":3,"item":"line\nbreak"}` + "\n"
+
+	writeOrders := func(res *Res, close bool) {
+		w, err := res.NDJSON()
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+
+		for _, order := range orders {
+			if err := w.Write(order); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			w.Flush()
+		}
+
+		if close {
+			w.Close()
+			if err := w.Write(orders[0]); err == nil {
+				t.Error("Expected an error writing to a closed writer")
+			}
+		}
+	}
+
+	app := NewApp()
+	app.Get("/export", func(req *Req, res *Res) {
+		writeOrders(res, true)
+	})
+	app.Get("/unclosed", func(req *Req, res *Res) {
+		writeOrders(res, false)
+	})
+
+	compressed := NewApp()
+	compressed.Use(Compress())
+	compressed.Get("/export", func(req *Req, res *Res) {
+		writeOrders(res, true)
+	})
+
+	tests := []struct {
+		name             string
+		app              *App
+		path             string
+		headers          map[string]string
+		expectedEncoding string
+	}{
+		{"Closed by the handler", app, "/export", nil, ""},
+		{"Closed once the handler returns", app, "/unclosed", nil, ""},
+		{"Compressed", compressed, "/export", map[string]string{"Accept-Encoding": "gzip"}, "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := parseCompressedResponse(t, mockRequest(tt.app, "GET", tt.path, "", tt.headers))
+
+			if len(resp.TransferEncoding) != 1 || resp.TransferEncoding[0] != "chunked" {
+				t.Errorf("Expected a chunked response, got %v", resp.TransferEncoding)
+			}
+
+			if resp.Header.Get("Content-Type") != "application/x-ndjson" {
+				t.Errorf("Expected application/x-ndjson, got %q", resp.Header.Get("Content-Type"))
+			}
+
+			if resp.Header.Get("Content-Encoding") != tt.expectedEncoding {
+				t.Errorf("Expected Content-Encoding %q, got %q", tt.expectedEncoding, resp.Header.Get("Content-Encoding"))
+			}
+
+			if body != expected {
+				t.Errorf("Expected body %q, got %q", expected, body)
+			}
+		})
+	}
+}
@@ -21,7 +21,7 @@ func (app *App) Use(args ...any) {
 	// If only one arg is passed, it's expected to be the middleware itself
 	// Otherwise, the first arg is expected to be the path and the other is the middleware
 	if len(args) == 1 {
-		m, ok := args[0].(func(*Req, *Res, func()))
+		m, ok := asMiddleware(args[0])
 		if !ok {
 			panic("Invalid argument: expected handler function")
 		}
@@ -29,7 +29,7 @@ func (app *App) Use(args ...any) {
 		middleware = m
 	} else if len(args) == 2 {
 		p, ok1 := args[0].(string)
-		m, ok2 := args[1].(func(*Req, *Res, func()))
+		m, ok2 := asMiddleware(args[1])
 		if !ok1 || !ok2 {
 			panic("Invalid arguments: expected string path and handler function")
 		}
@@ -59,7 +59,7 @@ func (router *Router) Use(args ...any) {
 	// If only one arg is passed, it's expected to be the middleware itself
 	// Otherwise, the first arg is expected to be the path and the other is the middleware
 	if len(args) == 1 {
-		m, ok := args[0].(func(*Req, *Res, func()))
+		m, ok := asMiddleware(args[0])
 		if !ok {
 			panic("Invalid argument: expected handler function")
 		}
@@ -67,7 +67,7 @@ func (router *Router) Use(args ...any) {
 		middleware = m
 	} else if len(args) == 2 {
 		p, ok1 := args[0].(string)
-		m, ok2 := args[1].(func(*Req, *Res, func()))
+		m, ok2 := asMiddleware(args[1])
 		if !ok1 || !ok2 {
 			panic("Invalid arguments: expected string path and handler function")
 		}
@@ -86,6 +86,19 @@ func (router *Router) Use(args ...any) {
 	router.middlewares = append(router.middlewares, mw)
 }
 
+// Return the passed argument as a middleware, whether it's a plain function or a `Middleware`
+// like the ones returned by the built-in middlewares
+func asMiddleware(arg any) (Middleware, bool) {
+	switch m := arg.(type) {
+	case Middleware:
+		return m, true
+	case func(*Req, *Res, func()):
+		return m, true
+	}
+
+	return nil, false
+}
+
 // This function constructs a chain of functions to be called one after the other
 func applyMiddleware(finalHandler Handler, router *Router) Handler {
 
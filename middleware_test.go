@@ -11,13 +11,13 @@ func TestMiddleware(t *testing.T) {
 
 	// Global middleware
 	app.Use(func(req *Req, res *Res, next func()) {
-		res.Send("GlobalMiddleware\n")
+		res.Header("X-Middleware", "GlobalMiddleware")
 		next()
 	})
 
 	// Path-specific middleware
 	app.Use("/api", func(req *Req, res *Res, next func()) {
-		res.Send("ApiMiddleware\n")
+		res.Header("X-Middleware", "ApiMiddleware")
 		next()
 	})
 
@@ -53,7 +53,7 @@ func TestMiddleware(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			response := mockRequest(app, "GET", tt.path, "")
+			response := mockRequest(app, "GET", tt.path, "", nil)
 
 			for _, expected := range tt.shouldContain {
 				if !strings.Contains(response, expected) {
@@ -78,7 +78,7 @@ func TestRouterMiddlewares(t *testing.T) {
 
 	// Global middleware
 	app.Use(func(req *Req, res *Res, next func()) {
-		res.Header("GlobalMiddleware", "true")
+		res.Header("Global-Middleware", "true")
 		next()
 	})
 
@@ -86,7 +86,7 @@ func TestRouterMiddlewares(t *testing.T) {
 
 	// Router middleware
 	router.Use(func(req *Req, res *Res, next func()) {
-		res.Header("RouterMiddleware", "true")
+		res.Header("Router-Middleware", "true")
 		next()
 	})
 
@@ -107,20 +107,20 @@ func TestRouterMiddlewares(t *testing.T) {
 		{
 			name:             "router with both middlewares",
 			path:             "/api/v1/test",
-			shouldContain:    []string{"GlobalMiddleware", "RouterMiddleware"},
+			shouldContain:    []string{"Global-Middleware", "Router-Middleware"},
 			shouldNotContain: []string{},
 		},
 		{
 			name:             "app route with only global middleware",
 			path:             "/test",
-			shouldContain:    []string{"GlobalMiddleware"},
-			shouldNotContain: []string{"RouterMiddleware"},
+			shouldContain:    []string{"Global-Middleware"},
+			shouldNotContain: []string{"Router-Middleware"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			response := mockRequest(app, "GET", tt.path, "")
+			response := mockRequest(app, "GET", tt.path, "", nil)
 
 			for _, expected := range tt.shouldContain {
 				if !strings.Contains(response, expected) {
@@ -138,3 +138,38 @@ func TestRouterMiddlewares(t *testing.T) {
 		})
 	}
 }
+
+// Test middlewares changing the buffered response after the handler
+func TestMiddlewarePostProcessing(t *testing.T) {
+	app := NewApp()
+
+	var loggedStatus int
+
+	// Logging middleware reads the final status after the handler
+	app.Use(func(req *Req, res *Res, next func()) {
+		next()
+		loggedStatus = res.StatusCode
+	})
+
+	// Post-processing middleware changes the status and headers after the handler
+	app.Use(func(req *Req, res *Res, next func()) {
+		next()
+		if res.StatusCode == 404 {
+			res.Status(410).Header("X-Post-Processed", "true")
+		}
+	})
+
+	app.Get("/gone", func(req *Req, res *Res) {
+		res.Status(404).Send("Gone")
+	})
+
+	response := mockRequest(app, "GET", "/gone", "", nil)
+
+	if !strings.HasPrefix(response, "HTTP/1.1 410 Gone") || !strings.Contains(response, "X-Post-Processed: true") {
+		t.Errorf("Expected the middleware to change the response after the handler, got: %s", response)
+	}
+
+	if loggedStatus != 410 {
+		t.Errorf("Expected the logged status to be 410, got %d", loggedStatus)
+	}
+}
@@ -0,0 +1,300 @@
+package zttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+const (
+	// Multipart bytes kept in memory before spooling files to disk (32 MB)
+	DefaultMultipartMemory = 32 << 20
+	// Max size of a whole multipart body, unless configured otherwise (128 MB)
+	DefaultMaxMultipartSize = 128 << 20
+)
+
+var (
+	// Returned when a single multipart file exceeds the app's `MaxFileSize`
+	ErrFileTooLarge = errors.New("multipart file too large")
+	// Returned when the whole multipart form exceeds the app's `MaxMultipartSize`
+	ErrMultipartTooLarge = errors.New("multipart form too large")
+
+	// Internal error for a part that exceeded the limit it was copied with
+	errPartTooLarge = errors.New("part too large")
+)
+
+type FormFile struct {
+	Filename string
+	// Only set when the file was small enough to be kept in memory, use Open() to read any file
+	Content []byte
+	Header  textproto.MIMEHeader
+	Size    int64
+
+	// The temp file the content was spooled to, if it didn't fit in memory
+	tmpPath string
+}
+
+// A parsed multipart form, its files are either kept in memory or spooled to temp files
+type MultipartForm struct {
+	Value map[string][]string
+	File  map[string][]*FormFile
+}
+
+// The limits applied while streaming a multipart form, zero means no limit
+type multipartLimits struct {
+	memory    int64
+	fileSize  int64
+	totalSize int64
+}
+
+// Open the form file content for reading, whether it's in memory or spooled to disk
+func (formFile *FormFile) Open() (io.ReadCloser, error) {
+	if formFile.tmpPath != "" {
+		return os.Open(formFile.tmpPath)
+	}
+
+	return io.NopCloser(bytes.NewReader(formFile.Content)), nil
+}
+
+// Remove all the temp files the form spooled to disk
+func (form *MultipartForm) RemoveAll() error {
+	var err error
+
+	for _, files := range form.File {
+		for _, file := range files {
+			if file.tmpPath == "" {
+				continue
+			}
+
+			if e := os.Remove(file.tmpPath); e != nil && !errors.Is(e, os.ErrNotExist) && err == nil {
+				err = e
+			}
+			file.tmpPath = ""
+		}
+	}
+
+	return err
+}
+
+// Checks if the request headers declare a multipart body
+func isMultipart(headers map[string]string) bool {
+	mediaType, _, err := mime.ParseMediaType(headers["Content-Type"])
+	return err == nil && strings.HasPrefix(mediaType, "multipart/")
+}
+
+// Checks if the request is multipart or not and stream the body into a multipart form
+func parseMultipart(headers map[string]string, body io.Reader, limits multipartLimits) (*MultipartForm, error) {
+	// Check if it's a multipart request or not
+	contentType := headers["Content-Type"]
+	if contentType == "" {
+		return nil, http.ErrNotMultipart
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, http.ErrNotMultipart
+	}
+
+	// Extract the boundary that separates between different parts
+	boundary := params["boundary"]
+	if boundary == "" {
+		log.Println("no boundary found in Content-Type")
+		return nil, http.ErrNotMultipart
+	}
+
+	// Create a multipart reader directly over the body stream and the parsed boundary
+	reader := multipart.NewReader(body, boundary)
+
+	form, err := readMultipartForm(reader, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	return form, nil
+}
+
+// Read the parts one by one from the multipart reader, keeping values and small files in memory
+// and spooling the rest of the files to temp files
+func readMultipartForm(reader *multipart.Reader, limits multipartLimits) (*MultipartForm, error) {
+	form := &MultipartForm{
+		Value: make(map[string][]string),
+		File:  make(map[string][]*FormFile),
+	}
+
+	memoryLeft := limits.memory
+	if memoryLeft <= 0 {
+		memoryLeft = DefaultMultipartMemory
+	}
+
+	var total int64
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			form.RemoveAll()
+			return nil, err
+		}
+
+		name := part.FormName()
+		if name == "" {
+			part.Close()
+			continue
+		}
+
+		// The most bytes this part may hold before breaking the total form limit
+		maxPart := int64(-1)
+		if limits.totalSize > 0 {
+			maxPart = limits.totalSize - total
+		}
+
+		// Plain values are always kept in memory, so they must fit in the memory left
+		if part.FileName() == "" {
+			maxValue := max(memoryLeft, 0)
+			if maxPart >= 0 && maxPart < maxValue {
+				maxValue = maxPart
+			}
+
+			var value bytes.Buffer
+			n, err := copyLimited(&value, part, maxValue)
+			part.Close()
+			if err != nil {
+				form.RemoveAll()
+				if errors.Is(err, errPartTooLarge) {
+					return nil, ErrMultipartTooLarge
+				}
+				return nil, err
+			}
+
+			total += n
+			memoryLeft -= n
+			form.Value[name] = append(form.Value[name], value.String())
+			continue
+		}
+
+		// Files are limited by both their own limit and what's left from the total form limit
+		maxFile := maxPart
+		if limits.fileSize > 0 && (maxFile < 0 || limits.fileSize < maxFile) {
+			maxFile = limits.fileSize
+		}
+
+		file, err := spoolFormFile(part, maxFile, memoryLeft)
+		part.Close()
+		if err != nil {
+			form.RemoveAll()
+
+			// Report which limit was actually exceeded
+			if errors.Is(err, ErrFileTooLarge) && (limits.fileSize <= 0 || maxFile < limits.fileSize) {
+				return nil, ErrMultipartTooLarge
+			}
+			return nil, err
+		}
+
+		total += file.Size
+		if file.tmpPath == "" {
+			memoryLeft -= file.Size
+		}
+		form.File[name] = append(form.File[name], file)
+	}
+
+	return form, nil
+}
+
+// Read a file part into memory, spooling it to a temp file once it exceeds the memory left
+func spoolFormFile(part *multipart.Part, maxSize, memoryLeft int64) (*FormFile, error) {
+	file := &FormFile{
+		Filename: part.FileName(),
+		Header:   part.Header,
+	}
+
+	// Try to keep the whole file in memory first
+	var content bytes.Buffer
+	memoryLimit := max(memoryLeft, 0)
+	if maxSize >= 0 && maxSize < memoryLimit {
+		memoryLimit = maxSize
+	}
+
+	n, err := io.CopyN(&content, part, memoryLimit+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	// The file fit in memory
+	if n <= memoryLimit {
+		file.Content = content.Bytes()
+		file.Size = n
+		return file, nil
+	}
+
+	// The file would already break the size limit, don't bother spooling it
+	if maxSize >= 0 && n > maxSize {
+		return nil, ErrFileTooLarge
+	}
+
+	// Spool what we have read so far and the rest of the part to a temp file
+	tmp, err := os.CreateTemp("", "zttp-multipart-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	remaining := int64(-1)
+	if maxSize >= 0 {
+		remaining = maxSize - n
+	}
+
+	written, err := content.WriteTo(tmp)
+	if err == nil {
+		var rest int64
+		rest, err = copyLimited(tmp, part, remaining)
+		written += rest
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		if errors.Is(err, errPartTooLarge) {
+			return nil, ErrFileTooLarge
+		}
+		return nil, err
+	}
+
+	file.tmpPath = tmp.Name()
+	file.Size = written
+	return file, nil
+}
+
+// Copy from src to dst failing with errPartTooLarge if more than limit bytes are available
+// A negative limit means no limit at all
+func copyLimited(dst io.Writer, src io.Reader, limit int64) (int64, error) {
+	if limit < 0 {
+		return io.Copy(dst, src)
+	}
+
+	n, err := io.Copy(dst, io.LimitReader(src, limit+1))
+	if err != nil {
+		return n, err
+	}
+
+	if n > limit {
+		return n, errPartTooLarge
+	}
+
+	return n, nil
+}
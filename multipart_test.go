@@ -0,0 +1,322 @@
+package zttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Helper to build a multipart body with the passed values and files
+func createMultipartForm(values map[string]string, files map[string][]byte) (string, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for k, v := range values {
+		writer.WriteField(k, v)
+	}
+
+	for name, content := range files {
+		part, _ := writer.CreateFormFile(name, name+".bin")
+		part.Write(content)
+	}
+
+	writer.Close()
+
+	return body.String(), fmt.Sprintf("multipart/form-data; boundary=%s", writer.Boundary())
+}
+
+func TestParseMultipartLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      map[string]string
+		files       map[string][]byte
+		limits      multipartLimits
+		expectedErr error
+		spooled     bool
+	}{
+		{
+			name:   "Within limits",
+			values: map[string]string{"username": "zkrallah"},
+			files:  map[string][]byte{"avatar": bytes.Repeat([]byte("a"), 100)},
+			limits: multipartLimits{fileSize: 1000, totalSize: 2000},
+		},
+		{
+			name:        "File exceeds the file limit",
+			files:       map[string][]byte{"avatar": bytes.Repeat([]byte("a"), 100)},
+			limits:      multipartLimits{fileSize: 50},
+			expectedErr: ErrFileTooLarge,
+		},
+		{
+			name:        "Spooled file exceeds the file limit",
+			files:       map[string][]byte{"avatar": bytes.Repeat([]byte("a"), 100)},
+			limits:      multipartLimits{memory: 10, fileSize: 50},
+			expectedErr: ErrFileTooLarge,
+		},
+		{
+			name:        "File exceeds the total limit",
+			files:       map[string][]byte{"avatar": bytes.Repeat([]byte("a"), 100)},
+			limits:      multipartLimits{fileSize: 1000, totalSize: 50},
+			expectedErr: ErrMultipartTooLarge,
+		},
+		{
+			name:        "Value exceeds the total limit",
+			values:      map[string]string{"bio": strings.Repeat("b", 100)},
+			limits:      multipartLimits{totalSize: 50},
+			expectedErr: ErrMultipartTooLarge,
+		},
+		{
+			name:        "Value exceeds the memory limit",
+			values:      map[string]string{"bio": strings.Repeat("b", 100)},
+			limits:      multipartLimits{memory: 50},
+			expectedErr: ErrMultipartTooLarge,
+		},
+		{
+			name:    "File above the memory threshold is spooled",
+			files:   map[string][]byte{"avatar": bytes.Repeat([]byte("a"), 100)},
+			limits:  multipartLimits{memory: 10},
+			spooled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := createMultipartForm(tt.values, tt.files)
+			headers := map[string]string{"Content-Type": contentType}
+
+			form, err := parseMultipart(headers, strings.NewReader(body), tt.limits)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("Expected error %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			defer form.RemoveAll()
+
+			for k, v := range tt.values {
+				if form.Value[k][0] != v {
+					t.Errorf("Value %s: expected %q, got %q", k, v, form.Value[k][0])
+				}
+			}
+
+			for name, content := range tt.files {
+				file := form.File[name][0]
+				if file.Size != int64(len(content)) {
+					t.Errorf("File %s: expected size %d, got %d", name, len(content), file.Size)
+				}
+
+				if tt.spooled && (file.tmpPath == "" || file.Content != nil) {
+					t.Errorf("File %s: expected to be spooled to disk", name)
+				}
+
+				rdr, err := file.Open()
+				if err != nil {
+					t.Fatalf("Failed to open file: %v", err)
+				}
+
+				got, _ := io.ReadAll(rdr)
+				rdr.Close()
+
+				if !bytes.Equal(got, content) {
+					t.Errorf("File %s: content mismatch", name)
+				}
+			}
+		})
+	}
+}
+
+func TestMultipartFormRemoveAll(t *testing.T) {
+	body, contentType := createMultipartForm(nil, map[string][]byte{"avatar": bytes.Repeat([]byte("a"), 100)})
+	headers := map[string]string{"Content-Type": contentType}
+
+	form, err := parseMultipart(headers, strings.NewReader(body), multipartLimits{memory: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tmpPath := form.File["avatar"][0].tmpPath
+	if _, err := os.Stat(tmpPath); err != nil {
+		t.Fatalf("Expected temp file to exist: %v", err)
+	}
+
+	if err := form.RemoveAll(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(tmpPath); !os.IsNotExist(err) {
+		t.Errorf("Expected temp file to be removed")
+	}
+}
+
+func TestSaveSpooledFile(t *testing.T) {
+	testDir := filepath.Join(os.TempDir(), "zttp_save_spooled_test")
+	defer os.RemoveAll(testDir)
+
+	content := bytes.Repeat([]byte("zttp"), 1024)
+	body, contentType := createMultipartForm(nil, map[string][]byte{"upload": content})
+
+	req := &Req{
+		app: &App{MultipartMemory: 16},
+		Headers: map[string]string{
+			"Content-Type": contentType,
+		},
+		bodyStream: strings.NewReader(body),
+	}
+
+	defer req.cleanup()
+
+	file, err := req.FormFile("upload")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := req.Save(file, testDir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	saved, err := os.ReadFile(filepath.Join(testDir, file.Filename))
+	if err != nil {
+		t.Fatalf("Failed to read saved file: %v", err)
+	}
+
+	if !bytes.Equal(saved, content) {
+		t.Errorf("Saved content mismatch")
+	}
+}
+
+// Test that multipart bodies are streamed from the socket and drained
+// so that the next request on the same connection is parsed correctly
+func TestMultipartKeepAlive(t *testing.T) {
+	app := NewApp()
+
+	app.Post("/upload", func(req *Req, res *Res) {
+		res.Send("username=" + req.FormValue("username"))
+	})
+
+	app.Post("/ignore", func(req *Req, res *Res) {
+		res.Send("ignored")
+	})
+
+	app.Get("/next", func(req *Req, res *Res) {
+		res.Send("next")
+	})
+
+	body, contentType := createMultipartForm(map[string]string{"username": "zkrallah"}, nil)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"Parsed body", "/upload", "username=zkrallah"},
+		{"Unread body", "/ignore", "ignored"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &MockConn{}
+			conn.inBuf = fmt.Appendf(nil,
+				"POST %s HTTP/1.1\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s"+
+					"GET /next HTTP/1.1\r\nConnection: close\r\n\r\n",
+				tt.path, contentType, len(body), body)
+
+			handleClient(conn, app)
+			output := string(conn.outBuf)
+
+			if !strings.Contains(output, tt.expected) {
+				t.Errorf("Expected response to contain %q, got: %s", tt.expected, output)
+			}
+
+			if !strings.HasSuffix(output, "next") {
+				t.Errorf("Expected the next request to be served, got: %s", output)
+			}
+		})
+	}
+}
+
+func TestMultipartLargeUpload(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), DefaultBodyLimit+(1<<20))
+
+	app := NewApp()
+	app.MultipartMemory = 1 << 20
+
+	app.Post("/upload", func(req *Req, res *Res) {
+		file, err := req.FormFile("video")
+		if err != nil {
+			res.Status(500).Send(err.Error())
+			return
+		}
+		res.Send(fmt.Sprintf("size=%d", file.Size))
+	})
+
+	app.Post("/limited", func(req *Req, res *Res) {
+		res.Send("limited")
+	}).BodyLimit(1 << 20)
+
+	body, contentType := createMultipartForm(nil, map[string][]byte{"video": content})
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"Larger than the app body limit", "/upload", fmt.Sprintf("size=%d", len(content))},
+		{"Larger than the route body limit", "/limited", "HTTP/1.1 413 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &MockConn{}
+			conn.inBuf = fmt.Appendf(nil, "POST %s HTTP/1.1\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s",
+				tt.path, contentType, len(body), body)
+
+			handleClient(conn, app)
+			output := string(conn.outBuf)
+
+			if !strings.Contains(output, tt.expected) {
+				t.Errorf("Expected response to contain %q, got: %s", tt.expected, output)
+			}
+		})
+	}
+
+	// Multipart bodies are exempt from the app body limit, but not from the multipart limit
+	if app.MaxMultipartSize != DefaultMaxMultipartSize {
+		t.Errorf("Expected the default multipart limit, got %d", app.MaxMultipartSize)
+	}
+
+	small := NewApp()
+	small.BodyLimit = 10
+	small.MaxMultipartSize = 1 << 20
+	small.Post("/upload", func(req *Req, res *Res) {
+		res.Send("uploaded")
+	})
+
+	conn := &MockConn{}
+	conn.inBuf = fmt.Appendf(nil, "POST /upload HTTP/1.1\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s",
+		contentType, len(body), body)
+	handleClient(conn, small)
+
+	if output := string(conn.outBuf); !strings.HasPrefix(output, "HTTP/1.1 413 ") {
+		t.Errorf("Expected 413 for a body larger than the multipart limit, got: %.100s", output)
+	}
+
+	// The read deadline is extended while the body streams, not only set once per request
+	conn = &MockConn{}
+	conn.inBuf = fmt.Appendf(nil, "POST /upload HTTP/1.1\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s",
+		contentType, len(body), body)
+	handleClient(conn, app)
+
+	if conn.readDeadlines < 10 {
+		t.Errorf("Expected the read deadline to be extended while streaming, set %d times", conn.readDeadlines)
+	}
+}
@@ -0,0 +1,231 @@
+package zttp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A single range of an `Accept` style header, like `text/html;level=1;q=0.8`
+type acceptRange struct {
+	// The lowercased range without its params, like `text/html`, `gzip`, or `en-us`
+	value string
+	// The media type params before the q-value, lowercased
+	params map[string]string
+	// The quality of the range, from 0 (not acceptable) to 1
+	q float64
+	// The position of the range in the header, to break ties in the client's order
+	index int
+}
+
+// An offered value that matched a range, ranked by how the client prefers it
+type acceptOffer struct {
+	value       string
+	q           float64
+	specificity int
+	rangeIndex  int
+	offerIndex  int
+}
+
+// Return how specifically the range matches the offered value, and false if it doesn't match at all
+type acceptMatcher func(r acceptRange, offer string) (int, bool)
+
+// Parse the ranges of an `Accept` style header in their header order
+// Params after the q-value are accept extensions and are ignored, as are empty ranges
+func parseAcceptRanges(header string) []acceptRange {
+	var ranges []acceptRange
+
+	for part := range strings.SplitSeq(header, ",") {
+		segments := strings.Split(part, ";")
+
+		value := strings.ToLower(strings.TrimSpace(segments[0]))
+		if value == "" {
+			continue
+		}
+
+		r := acceptRange{value: value, q: 1, index: len(ranges)}
+
+		for _, segment := range segments[1:] {
+			key, val, _ := strings.Cut(segment, "=")
+			key = strings.ToLower(strings.TrimSpace(key))
+			val = strings.Trim(strings.TrimSpace(val), `"`)
+
+			if key == "q" {
+				r.q = parseQuality(val)
+				break
+			}
+
+			if key != "" {
+				if r.params == nil {
+					r.params = make(map[string]string)
+				}
+				r.params[key] = strings.ToLower(val)
+			}
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	return ranges
+}
+
+// Parse a q-value, treating invalid ones as 1 like the lenient parsers of browsers do
+func parseQuality(value string) float64 {
+	q, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 1
+	}
+
+	return min(max(q, 0), 1)
+}
+
+// Return the offered value the client prefers according to the ranges, or an empty string if none is acceptable
+// Each offer gets the quality of the most specific range matching it, and offers with q=0 are excluded
+// The best offer has the highest quality, then the most specific range, then the earliest range,
+// and then the earliest position among the offers, as RFC 9110 leaves the final choice to the server
+func negotiate(ranges []acceptRange, offered []string, match acceptMatcher) string {
+	var candidates []acceptOffer
+
+	for i, offer := range offered {
+		best := acceptOffer{value: offer, specificity: -1, offerIndex: i}
+
+		for _, r := range ranges {
+			specificity, ok := match(r, offer)
+			if !ok {
+				continue
+			}
+
+			if specificity > best.specificity ||
+				(specificity == best.specificity && r.q > best.q) {
+				best.specificity = specificity
+				best.q = r.q
+				best.rangeIndex = r.index
+			}
+		}
+
+		if best.specificity >= 0 && best.q > 0 {
+			candidates = append(candidates, best)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.q != b.q {
+			return a.q > b.q
+		}
+		if a.specificity != b.specificity {
+			return a.specificity > b.specificity
+		}
+		if a.rangeIndex != b.rangeIndex {
+			return a.rangeIndex < b.rangeIndex
+		}
+		return a.offerIndex < b.offerIndex
+	})
+
+	return candidates[0].value
+}
+
+// Match a media range like `text/*` against an offered media type like `text/html;level=1`
+// Offers without a slash are extensions like `html` and are resolved to their MIME type first
+// An exact type ranks over a wildcard, and matching params rank over none
+func matchMediaType(r acceptRange, offer string) (int, bool) {
+	if !strings.Contains(offer, "/") {
+		offer = resolveContentType(offer)
+	}
+
+	offerType, offerParams := parseMediaType(offer)
+	rangeMain, rangeSub, _ := strings.Cut(r.value, "/")
+	offerMain, offerSub, _ := strings.Cut(offerType, "/")
+
+	specificity := 0
+
+	if rangeMain == offerMain {
+		specificity |= 4
+	} else if rangeMain != "*" {
+		return 0, false
+	}
+
+	if rangeSub == offerSub {
+		specificity |= 2
+	} else if rangeSub != "*" {
+		return 0, false
+	}
+
+	// The params of the range must all be present in the offer with the same values
+	for key, value := range r.params {
+		if offerParams[key] != value {
+			return 0, false
+		}
+	}
+
+	if len(r.params) > 0 {
+		specificity |= 1
+	}
+
+	return specificity, true
+}
+
+// Split a media type into its lowercased type and params
+func parseMediaType(mediaType string) (string, map[string]string) {
+	segments := strings.Split(mediaType, ";")
+	params := make(map[string]string)
+
+	for _, segment := range segments[1:] {
+		key, val, _ := strings.Cut(segment, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key != "" {
+			params[key] = strings.ToLower(strings.Trim(strings.TrimSpace(val), `"`))
+		}
+	}
+
+	return strings.ToLower(strings.TrimSpace(segments[0])), params
+}
+
+// Match a token range like a charset or an encoding, where only `*` is a wildcard
+func matchToken(r acceptRange, offer string) (int, bool) {
+	if strings.EqualFold(r.value, offer) {
+		return 1, true
+	}
+
+	return 0, r.value == "*"
+}
+
+// Match a language range against an offered language tag, following the filtering of RFC 4647
+// A range matches the tags it's a prefix of at a subtag boundary, so `en` matches `en-US`
+// As a fallback like the RFC 4647 lookup, a range also matches the shorter tags it truncates to,
+// so a client asking for `en-US` still gets `en`, though ranked below any filtered match
+func matchLanguage(r acceptRange, offer string) (int, bool) {
+	tag := strings.ToLower(offer)
+
+	switch {
+	case r.value == tag:
+		return 3, true
+	case strings.HasPrefix(tag, r.value+"-"):
+		return 2, true
+	case strings.HasPrefix(r.value, tag+"-"):
+		return 1, true
+	}
+
+	return 0, r.value == "*"
+}
+
+// Add the implicit `identity` coding of RFC 9110 to the ranges of an `Accept-Encoding` header
+// Identity is acceptable unless excluded by `identity;q=0`, or by `*;q=0` without an `identity` range,
+// and ranks below every coding the client listed
+func withIdentity(ranges []acceptRange) []acceptRange {
+	lowest := 1.0
+	for _, r := range ranges {
+		if r.value == "identity" || r.value == "*" {
+			return ranges
+		}
+		if r.q > 0 {
+			lowest = min(lowest, r.q)
+		}
+	}
+
+	return append(ranges, acceptRange{value: "identity", q: lowest, index: len(ranges)})
+}
@@ -0,0 +1,182 @@
+package zttp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptRanges(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []acceptRange
+	}{
+		{
+			input: "text/html;level=1;q=0.5, */*",
+			expected: []acceptRange{
+				{value: "text/html", params: map[string]string{"level": "1"}, q: 0.5, index: 0},
+				{value: "*/*", q: 1, index: 1},
+			},
+		},
+		{
+			input: "Text/HTML ; Q = 0.8 ; ext=ignored",
+			expected: []acceptRange{
+				{value: "text/html", q: 0.8, index: 0},
+			},
+		},
+		{
+			input: `text/plain;format="flowed", , gzip;q=0`,
+			expected: []acceptRange{
+				{value: "text/plain", params: map[string]string{"format": "flowed"}, q: 1, index: 0},
+				{value: "gzip", q: 0, index: 1},
+			},
+		},
+		{
+			input: "a;q=2, b;q=-1, c;q=oops",
+			expected: []acceptRange{
+				{value: "a", q: 1, index: 0},
+				{value: "b", q: 0, index: 1},
+				{value: "c", q: 1, index: 2},
+			},
+		},
+		{
+			input:    "",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := parseAcceptRanges(tt.input); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNegotiateMediaTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		offered  []string
+		expected string
+	}{
+		{"No header accepts the first offer", "", []string{"text/html", "application/json"}, "text/html"},
+		{"No offers", "text/html", nil, ""},
+		{"Highest q-value wins over header order", "text/html;q=0.5, application/json", []string{"text/html", "application/json"}, "application/json"},
+		{"Equal q-values follow the header order", "application/json, text/html", []string{"text/html", "application/json"}, "application/json"},
+		{"Equal ranges follow the offered order", "*/*", []string{"text/csv", "text/html"}, "text/csv"},
+		{"Exact type ranks over a wildcard", "text/*, text/html", []string{"text/css", "text/html"}, "text/html"},
+		{"Most specific range sets the quality", "text/*;q=0.9, text/html;q=0.1", []string{"text/html", "text/css"}, "text/css"},
+		{"Exclusion with q=0", "*/*, application/json;q=0", []string{"application/json", "text/html"}, "text/html"},
+		{"Only excluded offers", "application/json;q=0", []string{"application/json"}, ""},
+		{"Wildcard excluded", "text/html, */*;q=0", []string{"application/json"}, ""},
+		{"Params must match", "text/html;level=1", []string{"text/html;level=2", "text/html;level=1"}, "text/html;level=1"},
+		{"Range params rank over none", "text/html;q=0.5, text/html;level=1", []string{"text/html", "text/html;level=1"}, "text/html;level=1"},
+		{"Range without params matches offers with params", "text/html", []string{"text/html;charset=utf-8"}, "text/html;charset=utf-8"},
+		{"Case insensitive", "TEXT/HTML", []string{"text/html"}, "text/html"},
+		{"Extensions resolve to MIME types", "application/json", []string{"html", "json"}, "json"},
+		{"Type wildcard", "image/*", []string{"text/html", "image/png"}, "image/png"},
+		{"Type wildcard mismatch", "image/*", []string{"text/html"}, ""},
+		{"Browser header", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", []string{"application/json", "text/html"}, "text/html"},
+		{"Browser header prefers anything over nothing", "text/html,application/xml;q=0.9,*/*;q=0.8", []string{"application/json"}, "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Req{Headers: map[string]string{"Accept": tt.header}}
+			if got := req.Accepts(tt.offered...); got != tt.expected {
+				t.Errorf("Accepts(%v) with %q = %q, want %q", tt.offered, tt.header, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNegotiateCharsets(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		offered  []string
+		expected string
+	}{
+		{"No header", "", []string{"utf-8", "iso-8859-1"}, "utf-8"},
+		{"Highest q-value", "utf-8;q=0.5, iso-8859-1", []string{"utf-8", "iso-8859-1"}, "iso-8859-1"},
+		{"Wildcard follows the offered order", "*", []string{"iso-8859-1", "utf-8"}, "iso-8859-1"},
+		{"Exact range ranks over the wildcard", "*;q=0.5, utf-8;q=0.4", []string{"utf-8", "iso-8859-1"}, "iso-8859-1"},
+		{"Wildcard with an exclusion", "*, utf-8;q=0", []string{"utf-8", "iso-8859-1"}, "iso-8859-1"},
+		{"Unlisted charsets are not acceptable", "utf-8", []string{"iso-8859-1"}, ""},
+		{"Case insensitive", "UTF-8", []string{"utf-8"}, "utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Req{Headers: map[string]string{"Accept-Charset": tt.header}}
+			if got := req.AcceptsCharsets(tt.offered...); got != tt.expected {
+				t.Errorf("AcceptsCharsets(%v) with %q = %q, want %q", tt.offered, tt.header, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNegotiateEncodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		offered  []string
+		expected string
+	}{
+		{"No header", "", []string{"gzip", "identity"}, "gzip"},
+		{"Highest q-value", "gzip;q=0.5, br", []string{"gzip", "br"}, "br"},
+		{"Equal q-values follow the header order", "br, gzip", []string{"gzip", "br"}, "br"},
+		{"Identity is implicitly acceptable", "gzip", []string{"br", "identity"}, "identity"},
+		{"Identity ranks below listed codings", "gzip;q=0.5", []string{"identity", "gzip"}, "gzip"},
+		{"Identity excluded explicitly", "gzip, identity;q=0", []string{"identity"}, ""},
+		{"Identity excluded by the wildcard", "gzip, *;q=0", []string{"br", "identity"}, ""},
+		{"Identity listed despite the wildcard exclusion", "*;q=0, identity", []string{"gzip", "identity"}, "identity"},
+		{"Other codings stay acceptable when identity is excluded", "gzip, identity;q=0", []string{"gzip", "identity"}, "gzip"},
+		{"Wildcard", "*", []string{"br", "gzip"}, "br"},
+		{"Excluded coding", "gzip;q=0, deflate", []string{"gzip", "deflate"}, "deflate"},
+		{"Case insensitive", "GZIP", []string{"gzip"}, "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Req{Headers: map[string]string{"Accept-Encoding": tt.header}}
+			if got := req.AcceptsEncodings(tt.offered...); got != tt.expected {
+				t.Errorf("AcceptsEncodings(%v) with %q = %q, want %q", tt.offered, tt.header, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNegotiateLanguages(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		offered  []string
+		expected string
+	}{
+		{"No header", "", []string{"en", "fr"}, "en"},
+		{"Exact match", "fr", []string{"en", "fr"}, "fr"},
+		{"Highest q-value", "en;q=0.5, fr", []string{"en", "fr"}, "fr"},
+		{"Range matches longer tags", "en", []string{"fr", "en-GB"}, "en-GB"},
+		{"Range matches only at subtag boundaries", "en", []string{"eng"}, ""},
+		{"Exact tag ranks over a longer one", "en", []string{"en-US", "en"}, "en"},
+		{"Range falls back to a shorter tag", "en-US", []string{"fr", "en"}, "en"},
+		{"Longer tag ranks over the fallback", "en-US", []string{"en", "en-US"}, "en-US"},
+		{"Most specific range sets the quality", "en;q=0.9, en-GB;q=0.1", []string{"en-GB", "en-US"}, "en-US"},
+		{"Wildcard", "*", []string{"ja", "de"}, "ja"},
+		{"Wildcard with an exclusion", "*, de;q=0", []string{"de", "ja"}, "ja"},
+		{"Excluded language", "fr;q=0", []string{"fr-CA"}, ""},
+		{"Case insensitive", "EN-us", []string{"en-US"}, "en-US"},
+		{"No match", "de-DE", []string{"en", "fr"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Req{Headers: map[string]string{"Accept-Language": tt.header}}
+			if got := req.AcceptsLanguages(tt.offered...); got != tt.expected {
+				t.Errorf("AcceptsLanguages(%v) with %q = %q, want %q", tt.offered, tt.header, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package zttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+)
+
+// The content type of problem details, as RFC 9457 defines it
+const problemContentType = "application/problem+json"
+
+// An error response in the problem details format of RFC 9457
+// Example: res.Problem(zttp.Problem{Status: 404, Detail: "No user with the id 7"})
+type Problem struct {
+	// A URI reference identifying the problem type, "about:blank" by default
+	Type string
+	// A short summary of the problem type, the status text by default for "about:blank" problems
+	Title string
+	// The HTTP status code of the problem, 500 by default
+	Status int
+	// An explanation specific to this occurrence of the problem
+	Detail string
+	// A URI reference identifying this occurrence of the problem
+	Instance string
+	// Extra members of the problem, like a list of invalid params
+	// Extensions can't replace the standard members
+	Extensions map[string]any
+}
+
+// Create an "about:blank" problem with the passed status and detail, titled with the status text once it's sent
+func NewProblem(status int, detail string) Problem {
+	return Problem{Status: status, Detail: detail}
+}
+
+// Encode the problem as a single JSON object, with the extensions next to the standard members
+// Empty members are omitted, except for the type
+func (p Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+
+	members["type"] = p.Type
+	if p.Title != "" {
+		members["title"] = p.Title
+	}
+	if p.Status != 0 {
+		members["status"] = p.Status
+	}
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+
+	return json.Marshal(members)
+}
+
+// Fill the members the problem leaves empty with their defaults
+func (p Problem) withDefaults() Problem {
+	if p.Status < 100 || p.Status > 599 {
+		p.Status = 500
+	}
+
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+
+	// The title of "about:blank" problems should be the status text
+	if p.Title == "" && p.Type == "about:blank" {
+		p.Title = http.StatusText(p.Status)
+	}
+
+	return p
+}
+
+// This function sends the problem as an `application/problem+json` response with the problem's status
+// The problem is encoded with the app's `JSONEncoder`, like `res.Json()` does
+func (res *Res) Problem(p Problem) error {
+	if err := res.checkWritable(); err != nil {
+		return err
+	}
+
+	p = p.withDefaults()
+
+	var buf bytes.Buffer
+	if err := res.app().jsonEncoder().Encode(&buf, p, res.jsonIndent()); err != nil {
+		// The extensions are what the encoder can fail on, so the problem is sent without them
+		log.Println("Error parsing problem extensions:", err)
+		buf.Reset()
+		p.Extensions = nil
+		json.NewEncoder(&buf).Encode(p)
+	}
+
+	res.StatusCode = p.Status
+	res.ContentType = problemContentType
+
+	return res.send(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// This function sends an error response of the framework itself, as a problem if the app's
+// `ProblemDetails` is enabled, and as plain text otherwise
+// The detail is appended to the status text of plain text responses, like "Internal Server Error: JSON Marshal Failed"
+func (res *Res) sendError(code int, detail string) error {
+	// The error replaces whatever representation the handler was preparing, like a file's type or encoding,
+	// so the validators and range headers describing it are dropped too
+	for _, key := range []string{"Content-Type", "Content-Encoding", "Content-Disposition", "ETag", "Last-Modified", "Accept-Ranges"} {
+		res.Headers.Del(key)
+	}
+
+	// Only a 416 describes the size of the representation the ranges didn't fit in
+	if code != 416 {
+		res.Headers.Del("Content-Range")
+	}
+
+	if app := res.app(); app != nil && app.ProblemDetails {
+		return res.Problem(Problem{Status: code, Detail: detail})
+	}
+
+	res.StatusCode = code
+	res.ContentType = "text/plain; charset=utf-8"
+	return res.Send(errorText(code, detail))
+}
+
+// Send an error response of the framework directly to the socket, outside of any handler
+// It's a problem if the app's `ProblemDetails` is enabled, and plain text otherwise
+func sendError(socket net.Conn, app *App, code int, headers Header) error {
+	if app != nil && app.ProblemDetails {
+		body, _ := json.Marshal(Problem{Status: code}.withDefaults())
+		return sendResponse(socket, body, code, problemContentType, headers)
+	}
+
+	return sendResponse(socket, []byte(errorText(code, "")), code, "text/plain", headers)
+}
+
+// Return the plain text body of an error response
+func errorText(code int, detail string) string {
+	text := http.StatusText(code)
+	if detail != "" {
+		text += ": " + detail
+	}
+
+	return text
+}
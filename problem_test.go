@@ -0,0 +1,211 @@
+package zttp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Helper to decode the problem of a raw response
+func parseProblem(t *testing.T, response string) map[string]any {
+	if contentType := responseHeader(response, "Content-Type"); contentType != "application/problem+json" {
+		t.Fatalf("Expected application/problem+json, got %q in: %s", contentType, response)
+	}
+
+	_, body, _ := strings.Cut(response, "\r\n\r\n")
+
+	var problem map[string]any
+	if err := json.Unmarshal([]byte(body), &problem); err != nil {
+		t.Fatalf("Failed to decode the problem %q: %v", body, err)
+	}
+
+	return problem
+}
+
+func TestProblem(t *testing.T) {
+	tests := []struct {
+		name           string
+		problem        Problem
+		expectedStatus string
+		expected       map[string]any
+	}{
+		{
+			name:           "Defaults",
+			problem:        Problem{},
+			expectedStatus: "HTTP/1.1 500 Internal Server Error",
+			expected:       map[string]any{"type": "about:blank", "title": "Internal Server Error", "status": float64(500)},
+		},
+		{
+			name:           "Status text title",
+			problem:        NewProblem(404, "No user with the id 7"),
+			expectedStatus: "HTTP/1.1 404 Not Found",
+			expected:       map[string]any{"type": "about:blank", "title": "Not Found", "status": float64(404), "detail": "No user with the id 7"},
+		},
+		{
+			name: "Custom type with extensions",
+			problem: Problem{
+				Type:       "https://example.com/probs/out-of-credit",
+				Title:      "You do not have enough credit.",
+				Status:     403,
+				Instance:   "/account/12345/msgs/abc",
+				Extensions: map[string]any{"balance": 30, "status": "ignored"},
+			},
+			expectedStatus: "HTTP/1.1 403 Forbidden",
+			expected: map[string]any{
+				"type":     "https://example.com/probs/out-of-credit",
+				"title":    "You do not have enough credit.",
+				"status":   float64(403),
+				"instance": "/account/12345/msgs/abc",
+				"balance":  float64(30),
+			},
+		},
+		{
+			name:           "Custom type keeps an empty title",
+			problem:        Problem{Type: "https://example.com/probs/invalid", Status: 422},
+			expectedStatus: "HTTP/1.1 422 Unprocessable Entity",
+			expected:       map[string]any{"type": "https://example.com/probs/invalid", "status": float64(422)},
+		},
+		{
+			name:           "Unencodable extensions are dropped",
+			problem:        Problem{Status: 400, Extensions: map[string]any{"bad": make(chan int)}},
+			expectedStatus: "HTTP/1.1 400 Bad Request",
+			expected:       map[string]any{"type": "about:blank", "title": "Bad Request", "status": float64(400)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &MockConn{}
+			res := &Res{
+				Socket:     conn,
+				StatusCode: 200,
+				Headers:    make(Header),
+			}
+
+			if err := res.Problem(tt.problem); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			res.Flush()
+			response := string(conn.outBuf)
+
+			if !strings.HasPrefix(response, tt.expectedStatus) {
+				t.Errorf("Expected status %q, got: %s", tt.expectedStatus, response)
+			}
+
+			problem := parseProblem(t, response)
+			if len(problem) != len(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, problem)
+			}
+
+			for key, value := range tt.expected {
+				if problem[key] != value {
+					t.Errorf("Expected %s %v, got %v", key, value, problem[key])
+				}
+			}
+		})
+	}
+}
+
+func TestProblemDetails(t *testing.T) {
+	app := NewApp()
+	app.ProblemDetails = true
+	app.BodyLimit = 8
+
+	app.Get("/panic", func(req *Req, res *Res) {
+		panic("boom")
+	})
+	app.Get("/json", func(req *Req, res *Res) {
+		res.Json(make(chan int))
+	})
+	app.Get("/file", func(req *Req, res *Res) {
+		res.SendFile("missing.txt")
+	})
+	app.Post("/upload", func(req *Req, res *Res) {
+		res.Send("uploaded")
+	})
+
+	tests := []struct {
+		name           string
+		request        string
+		expectedStatus float64
+		expectedDetail string
+	}{
+		{"Unmatched route", "GET /missing HTTP/1.1\r\n\r\n", 404, ""},
+		{"Unsupported method", "TRACE /missing HTTP/1.1\r\n\r\n", 405, ""},
+		{"Handler panic", "GET /panic HTTP/1.1\r\n\r\n", 500, ""},
+		{"JSON failure", "GET /json HTTP/1.1\r\n\r\n", 500, "JSON Marshal Failed"},
+		{"Missing file", "GET /file HTTP/1.1\r\n\r\n", 404, ""},
+		{"Large body", "POST /upload HTTP/1.1\r\nContent-Length: 100\r\n\r\n", 413, ""},
+		{"Invalid content length", "POST /upload HTTP/1.1\r\nContent-Length: nope\r\n\r\n", 400, ""},
+		{"Invalid request line", "GARBAGE\r\n\r\n", 400, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &MockConn{}
+			conn.inBuf = []byte(tt.request)
+			handleClient(conn, app)
+
+			problem := parseProblem(t, string(conn.outBuf))
+
+			if problem["status"] != tt.expectedStatus {
+				t.Errorf("Expected status %v, got %v", tt.expectedStatus, problem["status"])
+			}
+
+			if detail, _ := problem["detail"].(string); detail != tt.expectedDetail {
+				t.Errorf("Expected detail %q, got %q", tt.expectedDetail, detail)
+			}
+		})
+	}
+
+	// Plain text stays the default
+	plain := NewApp()
+	response := mockRequest(plain, "GET", "/missing", "", nil)
+	if !strings.Contains(response, "Content-Type: text/plain") || !strings.HasSuffix(response, "Not Found") {
+		t.Errorf("Expected a plain text 404, got: %s", response)
+	}
+}
+
+func TestSendErrorReplacesRepresentation(t *testing.T) {
+	app := NewApp()
+	app.Get("/page", func(req *Req, res *Res) {
+		res.Format(Formatter{Type: "html", Handler: func() {
+			res.Render("index", nil)
+		}})
+	})
+	app.Get("/file", func(req *Req, res *Res) {
+		res.Type("png").Header("Content-Encoding", "gzip").Header("ETag", `"abc"`).
+			Header("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT").Header("Accept-Ranges", "bytes").
+			Header("Content-Range", "bytes 0-9/100").Attachment("image.png")
+		res.sendError(500, "")
+	})
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedBody string
+	}{
+		{"Render failure inside Format", "/page", "Internal Server Error: Template Render Failed"},
+		{"Error after the file headers", "/file", "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := mockRequest(app, "GET", tt.path, "", map[string]string{"Accept": "text/html"})
+
+			if contentType := responseHeader(response, "Content-Type"); contentType != "text/plain; charset=utf-8" {
+				t.Errorf("Expected a plain text error, got %q in: %s", contentType, response)
+			}
+
+			for _, key := range []string{"Content-Encoding", "Content-Disposition", "ETag", "Last-Modified", "Accept-Ranges", "Content-Range"} {
+				if strings.Contains(response, key+":") {
+					t.Errorf("Expected no %s, got: %s", key, response)
+				}
+			}
+
+			if !strings.HasSuffix(response, tt.expectedBody) {
+				t.Errorf("Expected body %q, got: %s", tt.expectedBody, response)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package zttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Max number of ranges served from a single request, more are answered with the full content
+const maxRanges = 100
+
+var (
+	errInvalidRange        = errors.New("invalid range")
+	errRangeNotSatisfiable = errors.New("range not satisfiable")
+)
+
+// A satisfiable byte range of the content
+type byteRange struct {
+	start  int64
+	length int64
+}
+
+// Return the `Content-Range` header value of the range
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// Return the headers of the range's part in a `multipart/byteranges` body
+func (r byteRange) mimeHeader(contentType string, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Range": {r.contentRange(size)},
+		"Content-Type":  {contentType},
+	}
+}
+
+// Counts the bytes written to it, to compute the length of a body before writing it
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+// Stream the content, answering conditional and range requests
+// Range requests need the content to be seekable, otherwise the full content is sent
+func (res *Res) serveContent(content io.Reader, size int64, modTime time.Time, etag string) {
+	// The client's copy is compressed if the full content will be, so its ETag is the compressed one
+	currentETag := etag
+	if res.shouldCompress(size) {
+		currentETag = encodedETag(etag, res.compression.encoding)
+	}
+
+	// The client already has this version of the content
+	if res.notModified(currentETag, modTime) {
+		res.Status(304).End()
+		return
+	}
+
+	seeker, seekable := content.(io.ReadSeeker)
+	if !seekable {
+		res.stream(content, size)
+		return
+	}
+
+	res.Header("Accept-Ranges", "bytes")
+
+	rangeHeader := ""
+	if res.Ctx != nil && res.Ctx.Req != nil {
+		rangeHeader = res.Ctx.Req.Header("Range")
+	}
+
+	// Send the full content if the ranges were requested from another version of it
+	if rangeHeader == "" || !res.ifRangeMatches(etag, modTime) {
+		res.stream(content, size)
+		return
+	}
+
+	ranges, err := parseRange(rangeHeader, size)
+	if errors.Is(err, errRangeNotSatisfiable) {
+		res.Headers.Del("Content-Type")
+		res.Header("Content-Range", fmt.Sprintf("bytes */%d", size))
+		res.ContentType = "text/plain; charset=utf-8"
+		res.sendError(416, "")
+		return
+	}
+
+	// Invalid ranges are ignored, and so are ranges asking for more than the whole content,
+	// as they can only be used to amplify the response
+	if err != nil || len(ranges) > maxRanges || sumRanges(ranges) > size {
+		res.stream(content, size)
+		return
+	}
+
+	if len(ranges) == 1 {
+		if _, err := seeker.Seek(ranges[0].start, io.SeekStart); err != nil {
+			res.sendError(500, "")
+			return
+		}
+
+		res.Header("Content-Range", ranges[0].contentRange(size))
+		res.Status(206).stream(seeker, ranges[0].length)
+		return
+	}
+
+	res.sendRanges(seeker, ranges, size)
+}
+
+// Send the ranges of the content as a `multipart/byteranges` body
+func (res *Res) sendRanges(content io.ReadSeeker, ranges []byteRange, size int64) {
+	if err := res.checkWritable(); err != nil {
+		log.Println("Error writing ranges:", err)
+		return
+	}
+
+	res.sent = true
+	res.committed = true
+
+	contentType := res.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// Write the parts' headers once to know the exact length of the body
+	var counter countingWriter
+	sizer := multipart.NewWriter(&counter)
+	for _, r := range ranges {
+		sizer.CreatePart(r.mimeHeader(contentType, size))
+	}
+	sizer.Close()
+
+	contentLength := int64(counter) + sumRanges(ranges)
+
+	res.Headers.Del("Content-Type")
+	res.ContentType = "multipart/byteranges; boundary=" + sizer.Boundary()
+	res.StatusCode = 206
+	writeHead(res.Socket, res.StatusCode, contentLength, res.ContentType, res.Headers)
+
+	mw := multipart.NewWriter(res.Socket)
+	mw.SetBoundary(sizer.Boundary())
+
+	for _, r := range ranges {
+		part, err := mw.CreatePart(r.mimeHeader(contentType, size))
+		if err != nil {
+			log.Println("Error writing range:", err)
+			return
+		}
+
+		if _, err := content.Seek(r.start, io.SeekStart); err != nil {
+			log.Println("Error seeking range:", err)
+			return
+		}
+
+		if _, err := io.CopyN(part, content, r.length); err != nil {
+			log.Println("Error writing range:", err)
+			return
+		}
+	}
+
+	mw.Close()
+}
+
+// Check the `If-Range` precondition against the passed validators
+// Ranges are served only if the client's copy is the same version of the content
+func (res *Res) ifRangeMatches(etag string, modTime time.Time) bool {
+	if res.Ctx == nil || res.Ctx.Req == nil {
+		return true
+	}
+
+	ifRange := strings.TrimSpace(res.Ctx.Req.Header("If-Range"))
+	if ifRange == "" {
+		return true
+	}
+
+	// Only strong ETags can validate ranges
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return etag != "" && !strings.HasPrefix(etag, "W/") && ifRange == etag
+	}
+
+	t, err := http.ParseTime(ifRange)
+
+	return err == nil && !modTime.IsZero() && modTime.Truncate(time.Second).Equal(t)
+}
+
+// Parse the `Range` header into the ranges it asks for from content of the passed size
+// Unsatisfiable ranges are skipped, and if none is left, errRangeNotSatisfiable is returned
+func parseRange(header string, size int64) ([]byteRange, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok {
+		return nil, errInvalidRange
+	}
+
+	var ranges []byteRange
+	unsatisfiable := false
+
+	for part := range strings.SplitSeq(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		first, last, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, errInvalidRange
+		}
+
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+		// A suffix range like `-500` asks for the last 500 bytes
+		if first == "" {
+			length, err := parseRangeInt(last)
+			if err != nil {
+				return nil, errInvalidRange
+			}
+
+			if length > size {
+				length = size
+			}
+
+			if length == 0 {
+				unsatisfiable = true
+				continue
+			}
+
+			ranges = append(ranges, byteRange{start: size - length, length: length})
+			continue
+		}
+
+		start, err := parseRangeInt(first)
+		if err != nil {
+			return nil, errInvalidRange
+		}
+
+		end := size - 1
+		if last != "" {
+			lastByte, err := parseRangeInt(last)
+			if err != nil || lastByte < start {
+				return nil, errInvalidRange
+			}
+
+			end = min(end, lastByte)
+		}
+
+		if start >= size {
+			unsatisfiable = true
+			continue
+		}
+
+		ranges = append(ranges, byteRange{start: start, length: end - start + 1})
+	}
+
+	if len(ranges) == 0 {
+		if unsatisfiable {
+			return nil, errRangeNotSatisfiable
+		}
+
+		return nil, errInvalidRange
+	}
+
+	return ranges, nil
+}
+
+// Parse a range position, which must be only digits, unlike what strconv accepts
+func parseRangeInt(s string) (int64, error) {
+	if s == "" || strings.TrimLeft(s, "0123456789") != "" {
+		return 0, errInvalidRange
+	}
+
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// Return the total length of the ranges
+func sumRanges(ranges []byteRange) int64 {
+	var total int64
+	for _, r := range ranges {
+		total += r.length
+	}
+
+	return total
+}
@@ -0,0 +1,220 @@
+package zttp
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected []byteRange
+		err      error
+	}{
+		{"bytes=0-4", []byteRange{{0, 5}}, nil},
+		{"bytes=5-", []byteRange{{5, 5}}, nil},
+		{"bytes=-3", []byteRange{{7, 3}}, nil},
+		{"bytes=-20", []byteRange{{0, 10}}, nil},
+		{"bytes=8-20", []byteRange{{8, 2}}, nil},
+		{"bytes=0-1, 4-5", []byteRange{{0, 2}, {4, 2}}, nil},
+		{"bytes=0-1,20-30", []byteRange{{0, 2}}, nil},
+		{"bytes=10-", nil, errRangeNotSatisfiable},
+		{"bytes=-0", nil, errRangeNotSatisfiable},
+		{"bytes=5-2", nil, errInvalidRange},
+		{"bytes=a-b", nil, errInvalidRange},
+		{"bytes=+1-2", nil, errInvalidRange},
+		{"bytes=", nil, errInvalidRange},
+		{"items=0-1", nil, errInvalidRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			ranges, err := parseRange(tt.header, 10)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+
+			if !reflect.DeepEqual(ranges, tt.expected) {
+				t.Errorf("Expected ranges %v, got %v", tt.expected, ranges)
+			}
+		})
+	}
+}
+
+func TestSendFileRanges(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "video.txt")
+	os.WriteFile(filePath, []byte("0123456789"), DefaultFilePerm)
+
+	modTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(filePath, modTime, modTime)
+
+	app := NewApp()
+	app.Get("/video", func(req *Req, res *Res) {
+		res.SendFile(filePath)
+	})
+	app.Get("/tagged", func(req *Req, res *Res) {
+		res.Header("ETag", `"v1"`)
+		res.SendFile(filePath)
+	})
+	app.Get("/missing", func(req *Req, res *Res) {
+		res.SendFile(filepath.Join(dir, "missing.txt"))
+	})
+
+	tests := []struct {
+		name          string
+		path          string
+		headers       map[string]string
+		shouldContain []string
+	}{
+		{
+			name:          "Full content",
+			path:          "/video",
+			shouldContain: []string{"HTTP/1.1 200 OK", "Accept-Ranges: bytes", "Content-Length: 10\r\n", "0123456789"},
+		},
+		{
+			name:          "Single range",
+			path:          "/video",
+			headers:       map[string]string{"Range": "bytes=2-5"},
+			shouldContain: []string{"HTTP/1.1 206 Partial Content", "Content-Range: bytes 2-5/10", "Content-Length: 4\r\n", "\r\n\r\n2345"},
+		},
+		{
+			name:          "Suffix range",
+			path:          "/video",
+			headers:       map[string]string{"Range": "bytes=-3"},
+			shouldContain: []string{"HTTP/1.1 206 Partial Content", "Content-Range: bytes 7-9/10"},
+		},
+		{
+			name:          "Unsatisfiable range",
+			path:          "/video",
+			headers:       map[string]string{"Range": "bytes=20-30"},
+			shouldContain: []string{"HTTP/1.1 416 Requested Range Not Satisfiable", "Content-Range: bytes */10"},
+		},
+		{
+			name:          "Invalid range is ignored",
+			path:          "/video",
+			headers:       map[string]string{"Range": "bytes=x-y"},
+			shouldContain: []string{"HTTP/1.1 200 OK", "0123456789"},
+		},
+		{
+			name:          "Overlapping ranges larger than the content are ignored",
+			path:          "/video",
+			headers:       map[string]string{"Range": "bytes=0-9,0-9"},
+			shouldContain: []string{"HTTP/1.1 200 OK", "0123456789"},
+		},
+		{
+			name:          "Matching If-Range date",
+			path:          "/video",
+			headers:       map[string]string{"Range": "bytes=0-1", "If-Range": modTime.Format(http.TimeFormat)},
+			shouldContain: []string{"HTTP/1.1 206 Partial Content"},
+		},
+		{
+			name:          "Stale If-Range date",
+			path:          "/video",
+			headers:       map[string]string{"Range": "bytes=0-1", "If-Range": modTime.Add(-time.Hour).Format(http.TimeFormat)},
+			shouldContain: []string{"HTTP/1.1 200 OK", "0123456789"},
+		},
+		{
+			name:          "Matching If-Range ETag",
+			path:          "/tagged",
+			headers:       map[string]string{"Range": "bytes=0-1", "If-Range": `"v1"`},
+			shouldContain: []string{"HTTP/1.1 206 Partial Content"},
+		},
+		{
+			name:          "Stale If-Range ETag",
+			path:          "/tagged",
+			headers:       map[string]string{"Range": "bytes=0-1", "If-Range": `"v0"`},
+			shouldContain: []string{"HTTP/1.1 200 OK", "0123456789"},
+		},
+		{
+			name:          "Missing file",
+			path:          "/missing",
+			shouldContain: []string{"HTTP/1.1 404 Not Found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := mockRequest(app, "GET", tt.path, "", tt.headers)
+
+			for _, expected := range tt.shouldContain {
+				if !strings.Contains(response, expected) {
+					t.Errorf("Expected response to contain %q, got: %s", expected, response)
+				}
+			}
+		})
+	}
+}
+
+func TestMultipleRanges(t *testing.T) {
+	root := createStaticTree(t, map[string]string{"data.txt": "0123456789"})
+
+	app := NewApp()
+	app.Static("/", root)
+
+	response := mockRequest(app, "GET", "/data.txt", "", map[string]string{"Range": "bytes=0-1,-2"})
+
+	head, body, _ := strings.Cut(response, "\r\n\r\n")
+	if !strings.HasPrefix(head, "HTTP/1.1 206 Partial Content") {
+		t.Fatalf("Expected 206, got: %s", response)
+	}
+
+	var contentType string
+	for line := range strings.SplitSeq(head, "\r\n") {
+		if value, ok := strings.CutPrefix(line, "Content-Type: "); ok {
+			contentType = value
+		}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Expected a multipart/byteranges content type, got %q", contentType)
+	}
+
+	if !strings.Contains(head, "Content-Length: "+strconv.Itoa(len(body))+"\r\n") {
+		t.Errorf("Expected the content length to match the body length %d, got: %s", len(body), head)
+	}
+
+	expected := []struct {
+		contentRange string
+		content      string
+	}{
+		{"bytes 0-1/10", "01"},
+		{"bytes 8-9/10", "89"},
+	}
+
+	mr := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	for _, exp := range expected {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+
+		if got := part.Header.Get("Content-Range"); got != exp.contentRange {
+			t.Errorf("Expected Content-Range %q, got %q", exp.contentRange, got)
+		}
+
+		if got := part.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+			t.Errorf("Expected the part to keep the file content type, got %q", got)
+		}
+
+		content, _ := io.ReadAll(part)
+		if string(content) != exp.content {
+			t.Errorf("Expected part content %q, got %q", exp.content, content)
+		}
+	}
+
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("Expected only two parts, got error %v", err)
+	}
+}
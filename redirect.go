@@ -0,0 +1,142 @@
+package zttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// Returned when a redirect is given a status that isn't a redirect status
+	ErrRedirectStatus = errors.New("invalid redirect status")
+	// Returned when `SafeRedirects` is enabled and the redirect targets another host
+	ErrUnsafeRedirect = errors.New("redirect to a host that isn't allowed")
+)
+
+// Redirect the client to the passed URL, with 302 Found unless another status is passed
+// Use 303 to turn the next request into a GET, or 307 and 308 to keep its method and body
+// If the app has `SafeRedirects` enabled, URLs on other hosts are answered with 400 and fail with `ErrUnsafeRedirect`
+// Example: res.Redirect("/login") or res.Redirect("/new-path", 301)
+func (res *Res) Redirect(target string, status ...int) error {
+	code := 302
+	if len(status) > 0 {
+		code = status[0]
+	}
+
+	switch code {
+	case 301, 302, 303, 307, 308:
+	default:
+		return fmt.Errorf("%w: %d", ErrRedirectStatus, code)
+	}
+
+	if target == "" {
+		return errors.New("empty redirect URL")
+	}
+
+	if err := res.checkWritable(); err != nil {
+		return err
+	}
+
+	if req := res.request(); req != nil && req.app != nil && req.app.SafeRedirects {
+		if !req.isAllowedRedirect(target, req.app.RedirectAllowedHosts) {
+			res.sendError(400, "redirect target isn't allowed")
+			return ErrUnsafeRedirect
+		}
+	}
+
+	res.Headers.Set("Location", target)
+	res.StatusCode = code
+	res.ContentType = "text/plain; charset=utf-8"
+
+	return res.send([]byte(http.StatusText(code) + ". Redirecting to " + target))
+}
+
+// Redirect the client back to the page it came from, as its `Referer` header tells
+// The fallback URL is used if there's no `Referer`, or if `SafeRedirects` doesn't allow it
+// Example: res.RedirectBack("/")
+func (res *Res) RedirectBack(fallback string, status ...int) error {
+	target := fallback
+
+	if req := res.request(); req != nil {
+		if referer := req.Header("Referer"); referer != "" {
+			if req.app == nil || !req.app.SafeRedirects || req.isAllowedRedirect(referer, req.app.RedirectAllowedHosts) {
+				target = referer
+			}
+		}
+	}
+
+	return res.Redirect(target, status...)
+}
+
+// Redirect the client to the route registered with the passed name, filling its params
+// Example: res.RedirectToRoute("user", map[string]string{"id": "7"})
+func (res *Res) RedirectToRoute(name string, params map[string]string, status ...int) error {
+	req := res.request()
+	if req == nil || req.app == nil {
+		return fmt.Errorf("no route named %q", name)
+	}
+
+	target, err := req.app.URL(name, params)
+	if err != nil {
+		return err
+	}
+
+	return res.Redirect(target, status...)
+}
+
+// Return the request of the response, if it has one
+func (res *Res) request() *Req {
+	if res.Ctx == nil {
+		return nil
+	}
+
+	return res.Ctx.Req
+}
+
+// Check if the redirect target is a relative URL, or an absolute one on the request host or an allowed host
+func (req *Req) isAllowedRedirect(target string, allowedHosts []string) bool {
+	// Browsers drop tabs and line breaks and read backslashes as slashes,
+	// so "/\evil.com" would otherwise pass as a relative URL while leading to another host
+	target = strings.Map(func(r rune) rune {
+		switch r {
+		case '\t', '\r', '\n':
+			return -1
+		case '\\':
+			return '/'
+		}
+		return r
+	}, strings.TrimSpace(target))
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+
+	if u.Scheme == "" && u.Host == "" {
+		return true
+	}
+
+	// Schemes like `javascript:` are never safe, and neither is a scheme without a host
+	// Scheme relative URLs like "//example.com" keep the scheme, so only their host is checked
+	if (u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+
+	if host := req.Host(); host != "" && strings.EqualFold(u.Host, host) {
+		return true
+	}
+
+	for _, allowed := range allowedHosts {
+		if strings.Contains(allowed, ":") {
+			if strings.EqualFold(u.Host, allowed) {
+				return true
+			}
+		} else if strings.EqualFold(u.Hostname(), allowed) {
+			return true
+		}
+	}
+
+	return false
+}
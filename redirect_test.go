@@ -0,0 +1,162 @@
+package zttp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRedirect(t *testing.T) {
+	app := NewApp()
+
+	app.Get("/old", func(req *Req, res *Res) {
+		res.Redirect("/new")
+	})
+	app.Get("/moved", func(req *Req, res *Res) {
+		res.Redirect("/new", 301)
+	})
+	app.Post("/submit", func(req *Req, res *Res) {
+		res.Redirect("/result", 303)
+	})
+	app.Get("/invalid", func(req *Req, res *Res) {
+		if err := res.Redirect("/new", 200); errors.Is(err, ErrRedirectStatus) {
+			res.Status(500).Send("invalid status")
+		}
+	})
+	app.Get("/back", func(req *Req, res *Res) {
+		res.RedirectBack("/home")
+	})
+	app.Get("/users/:id", func(req *Req, res *Res) {
+		res.Send("user " + req.Param("id"))
+	}).Name("user")
+	app.Get("/to-user", func(req *Req, res *Res) {
+		res.RedirectToRoute("user", map[string]string{"id": "a b"}, 307)
+	})
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		headers          map[string]string
+		expectedStatus   string
+		expectedLocation string
+	}{
+		{"Default status", "GET", "/old", nil, "HTTP/1.1 302 Found", "/new"},
+		{"Permanent redirect", "GET", "/moved", nil, "HTTP/1.1 301 Moved Permanently", "/new"},
+		{"See other", "POST", "/submit", nil, "HTTP/1.1 303 See Other", "/result"},
+		{"Invalid status", "GET", "/invalid", nil, "HTTP/1.1 500 Internal Server Error", ""},
+		{"Back to referer", "GET", "/back", map[string]string{"Referer": "/previous"}, "HTTP/1.1 302 Found", "/previous"},
+		{"Back without referer", "GET", "/back", nil, "HTTP/1.1 302 Found", "/home"},
+		{"Named route", "GET", "/to-user", nil, "HTTP/1.1 307 Temporary Redirect", "/users/a%20b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := mockRequest(app, tt.method, tt.path, "", tt.headers)
+
+			if !strings.HasPrefix(response, tt.expectedStatus) {
+				t.Errorf("Expected status %q, got: %s", tt.expectedStatus, response)
+			}
+
+			if location := responseHeader(response, "Location"); location != tt.expectedLocation {
+				t.Errorf("Expected Location %q, got %q", tt.expectedLocation, location)
+			}
+		})
+	}
+}
+
+func TestSafeRedirects(t *testing.T) {
+	app := NewApp()
+	app.SafeRedirects = true
+	app.RedirectAllowedHosts = []string{"trusted.com", "api.trusted.com:8443"}
+
+	var redirectErr error
+	app.Get("/go", func(req *Req, res *Res) {
+		redirectErr = res.Redirect(req.Query("to"))
+	})
+	app.Get("/back", func(req *Req, res *Res) {
+		res.RedirectBack("/home")
+	})
+
+	tests := []struct {
+		target  string
+		allowed bool
+	}{
+		{"/dashboard", true},
+		{"dashboard?tab=1", true},
+		{"http://localhost:8080/page", true},
+		{"HTTPS://TRUSTED.COM/page", true},
+		{"https://trusted.com:9000/page", true},
+		{"https://api.trusted.com:8443/page", true},
+		{"https://api.trusted.com/page", false},
+		{"https://evil.com", false},
+		{"//evil.com", false},
+		{"//localhost:8080/page", true},
+		{`/\evil.com`, false},
+		{"/%09/evil.com", true},
+		{"javascript:alert(1)", false},
+		{"https://trusted.com.evil.com", false},
+		{"https://trusted.com@evil.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			req := &Req{Headers: map[string]string{"Host": "localhost:8080"}}
+			if got := req.isAllowedRedirect(tt.target, app.RedirectAllowedHosts); got != tt.allowed {
+				t.Errorf("isAllowedRedirect(%q) = %v, want %v", tt.target, got, tt.allowed)
+			}
+		})
+	}
+
+	headers := map[string]string{"Host": "localhost:8080"}
+
+	response := mockRequest(app, "GET", "/go?to=https://evil.com", "", headers)
+	if !strings.HasPrefix(response, "HTTP/1.1 400 Bad Request") || responseHeader(response, "Location") != "" {
+		t.Errorf("Expected the unsafe redirect to be refused, got: %s", response)
+	}
+
+	if !errors.Is(redirectErr, ErrUnsafeRedirect) {
+		t.Errorf("Expected ErrUnsafeRedirect, got %v", redirectErr)
+	}
+
+	headers["Referer"] = "https://evil.com/phishing"
+	response = mockRequest(app, "GET", "/back", "", headers)
+	if location := responseHeader(response, "Location"); location != "/home" {
+		t.Errorf("Expected the fallback for an unsafe referer, got %q", location)
+	}
+}
+
+func TestAppURL(t *testing.T) {
+	app := NewApp()
+	app.Get("/posts/:id/comments/:comment", func(req *Req, res *Res) {}).Name("comment")
+
+	router := app.NewRouter("/files")
+	router.Get("/*", func(req *Req, res *Res) {}).Name("file")
+
+	tests := []struct {
+		name     string
+		route    string
+		params   map[string]string
+		expected string
+		wantErr  bool
+	}{
+		{"Params", "comment", map[string]string{"id": "1", "comment": "2"}, "/posts/1/comments/2", false},
+		{"Escaped params", "comment", map[string]string{"id": "a/b", "comment": "2"}, "/posts/a%2Fb/comments/2", false},
+		{"Missing param", "comment", map[string]string{"id": "1"}, "", true},
+		{"Catch-all", "file", map[string]string{"*": "docs/my file.txt"}, "/files/docs/my%20file.txt", false},
+		{"Unknown route", "missing", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := app.URL(tt.route, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -3,50 +3,84 @@ package zttp
 import (
 	"bufio"
 	"bytes"
-	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"mime"
-	"mime/multipart"
 	"net"
 	"net/http"
-	"net/textproto"
 	"os"
-	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 )
 
 const (
+	// Unread body bytes drained after the handler before giving up on the connection (256 KB)
+	maxBodyDrain = 256 << 10
+
 	// drwxr-xr-x
 	DefaultDirPerm = os.ModeDir | 0755
 	// -rw-------
 	DefaultFilePerm = 0600
 )
 
-type AcceptPart struct {
-	part string
-	q    float32
-}
+var (
+	// Returned when reading from a closed body reader
+	errBodyClosed = errors.New("read on closed request body")
+	// Returned when the request has too many header fields or a too long header line
+	errHeadersTooLarge = errors.New("request header fields too large")
+	// Returned when the `Content-Length` request header isn't a valid length
+	errInvalidContentLength = errors.New("invalid content length")
 
-type FormFile struct {
-	Filename string
-	Content  []byte
-	Header   textproto.MIMEHeader
-}
+	// Returned when `req.Bind()` can't decode the content type of the request body
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+)
 
 type Req struct {
 	LocalAddress string
 	Method       string
 	Path         string
-	Body         string
-	Headers      map[string]string
-	Params       map[string]string
-	Queries      map[string]string
-	Cookies      map[string]string
+	// The buffered request body, empty for multipart bodies and bodies streamed with `StreamRequestBody`
+	// until a body helper reads them, use `req.BodyString()` to get the body in every case
+	Body    string
+	Headers map[string]string
+	Params  map[string]string
+	Queries map[string]string
+	Cookies map[string]string
 	*Ctx
+
+	// The app this request is associated with
+	app *App
+
+	// The unread body stream of requests whose body isn't buffered into `Body`, like multipart uploads
+	bodyStream io.Reader
+	// Whether the rest of the body stream was already read into `Body`
+	bodyLoaded bool
+
+	// The multipart form parsed from the body, cached after the first parse
+	multipartForm *MultipartForm
+	multipartErr  error
+	multipartDone bool
+}
+
+// An io.ReadCloser over the body stream that stops reading once closed
+type bodyReader struct {
+	rdr    io.Reader
+	closed bool
+}
+
+func (body *bodyReader) Read(p []byte) (int, error) {
+	if body.closed {
+		return 0, errBodyClosed
+	}
+
+	return body.rdr.Read(p)
+}
+
+func (body *bodyReader) Close() error {
+	body.closed = true
+	return nil
 }
 
 // Return the value of the passed header key
@@ -64,10 +98,85 @@ func (req *Req) Query(key string) string {
 	return req.Queries[key]
 }
 
-// Parse the request body into the target struct
+// Parse the request body into the target struct, decoded with the app's `JSONDecoder`
 // Note that the target MUST be a pointer
 func (req *Req) ParseJson(target any) error {
-	return json.Unmarshal([]byte(req.Body), target)
+	body, err := req.BodyString()
+	if err != nil {
+		return err
+	}
+
+	return req.app.jsonDecoder().Decode(strings.NewReader(body), target)
+}
+
+// Parse the XML request body into the target struct
+// Note that the target MUST be a pointer
+func (req *Req) ParseXML(target any) error {
+	body, err := req.BodyString()
+	if err != nil {
+		return err
+	}
+
+	return xml.Unmarshal([]byte(body), target)
+}
+
+// Parse the request body into the target struct according to its `Content-Type`
+// JSON types like `application/json` and `application/problem+json` are parsed as JSON,
+// XML types like `application/xml` and `text/xml` as XML, and a body without a type as JSON
+// Other types fail with `ErrUnsupportedMediaType`, which handlers usually answer with 415
+// Note that the target MUST be a pointer
+func (req *Req) Bind(target any) error {
+	mediaType, _, _ := strings.Cut(req.Header("Content-Type"), ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	switch {
+	case mediaType == "", mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return req.ParseJson(target)
+	case mediaType == "application/xml", mediaType == "text/xml", strings.HasSuffix(mediaType, "+xml"):
+		return req.ParseXML(target)
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+}
+
+// Return a reader over the request body
+// If the body is streamed, like when the app's `StreamRequestBody` is set, the reader reads
+// from the socket on demand, otherwise it reads from the already buffered `Body`
+// Whatever the handler doesn't read is drained after it returns to keep the connection usable
+func (req *Req) BodyReader() io.ReadCloser {
+	if req.bodyStream != nil && !req.bodyLoaded {
+		return &bodyReader{rdr: req.bodyStream}
+	}
+
+	return io.NopCloser(strings.NewReader(req.Body))
+}
+
+// Return the whole request body, reading the rest of it from the socket if it's streamed
+// Unlike `Body`, it also has the body of multipart requests and of apps with `StreamRequestBody` set
+// Parts of the body already read, through `req.BodyReader()` or by parsing a multipart form, aren't included
+func (req *Req) BodyString() (string, error) {
+	if err := req.loadBody(); err != nil {
+		return "", err
+	}
+
+	return req.Body, nil
+}
+
+// Read the rest of the body stream into `Body`, if the body is streamed and wasn't read yet
+func (req *Req) loadBody() error {
+	if req.bodyStream == nil || req.bodyLoaded {
+		return nil
+	}
+
+	req.bodyLoaded = true
+
+	rest, err := io.ReadAll(req.bodyStream)
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+
+	req.Body += string(rest)
+	return nil
 }
 
 // Return true when the response is still “fresh” in the client's cache.
@@ -78,17 +187,12 @@ func (req *Req) ParseJson(target any) error {
 // This logic is heavily inspired by the official gofiber source code, with some touches of mine:
 // https://github.com/gofiber/fiber/blob/main/ctx.go
 func (req *Req) Fresh() bool {
-	etagMatched := true
-	modifiedSinceMatched := true
-	etagMissing := false
-
 	// Check for conditional request headers
 	modifiedSince := req.Header("If-Modified-Since")
 	noneMatch := req.Header("If-None-Match")
 
 	// The request is unconditional
 	if modifiedSince == "" && noneMatch == "" {
-		log.Println("The request is unconditional")
 		return false
 	}
 
@@ -96,72 +200,31 @@ func (req *Req) Fresh() bool {
 	// request is intended to be an end-to-end request
 	cacheControl := req.Header("Cache-Control")
 	if cacheControl != "" && hasNoCacheDirective(cacheControl) {
-		log.Println("The request has the `Cache-Control: no-cache` header")
 		return false
 	}
 
-	// Start comparing conditional request headers with response headers
+	// Check `Etag` and `If-None-Match` headers first, they take precedence over `If-Modified-Since`
 	if noneMatch != "" && noneMatch != "*" {
-		etags := req.Ctx.Res.Headers["ETag"]
-		if len(etags) == 0 {
-			log.Println("`ETag` response header not found")
-			etagMatched = false
-		}
-		etag := etags[0]
-
-		if etag == "" {
-			log.Println("`ETag` response header not found")
-			etagMatched = false
-		}
-
-		// Check `Etag` and `If-None-Match` headers first
-		if isEtagStale(etag, []byte(noneMatch)) {
-			log.Println("`ETAG` response header didn't match with `If-None-Match` request header")
-			etagMatched = false
-		}
-	} else {
-		etagMatched = false
-		etagMissing = true
+		etag := req.Ctx.Res.Headers.Get("ETag")
+		return etag != "" && !isEtagStale(etag, []byte(noneMatch))
 	}
 
 	if modifiedSince != "" {
-		lastModifiedHeaders := req.Ctx.Res.Headers["Last-Modified"]
-		if len(lastModifiedHeaders) == 0 {
-			log.Println("`Last-Modified` response header not found")
-			modifiedSinceMatched = false
+		lastModifiedTime, err := http.ParseTime(req.Ctx.Res.Headers.Get("Last-Modified"))
+		if err != nil {
+			return false
 		}
 
-		lastModified := lastModifiedHeaders[0]
-
-		if lastModified == "" {
-			log.Println("`Last-Modified` response header not found")
-			modifiedSinceMatched = false
+		modifiedSinceTime, err := http.ParseTime(modifiedSince)
+		if err != nil {
+			return false
 		}
 
-		if lastModified != "" {
-			lastModifiedTime, err := http.ParseTime(lastModified)
-			if err != nil {
-				log.Println("Could not parse last modified time")
-				modifiedSinceMatched = false
-			}
-
-			modifiedSinceTime, err := http.ParseTime(modifiedSince)
-			if err != nil {
-				log.Println("Could not parse modified since time")
-				modifiedSinceMatched = false
-			}
-
-			// Return true if modifiedSinceTime is not after lastModifiedTime
-			if lastModifiedTime.After(modifiedSinceTime) {
-				log.Println("Resource modified")
-				modifiedSinceMatched = false
-			} else {
-				log.Println("Resource wasn't modified")
-			}
-		}
+		// Fresh if the resource wasn't modified after the client's copy
+		return !lastModifiedTime.After(modifiedSinceTime)
 	}
 
-	return etagMatched || (etagMissing && modifiedSinceMatched)
+	return true
 }
 
 // If the request is not fresh, then it's stale
@@ -171,7 +234,7 @@ func (req *Req) Stale() bool {
 
 // Return the reference to the app this request is associated with
 func (req *Req) App() *App {
-	return req.App()
+	return req.app
 }
 
 // Return the base URL of the request derived from the `Host` HTTP header
@@ -180,17 +243,29 @@ func (req *Req) Host() string {
 	return req.Header("Host")
 }
 
+// Parse the request body as a multipart form and return it
+// The form is parsed only once per request and cached for later calls
+// Multipart bodies are streamed straight from the socket, spooling large files to disk
+func (req *Req) MultipartForm() (*MultipartForm, error) {
+	if !req.multipartDone {
+		body := req.BodyReader()
+
+		req.multipartForm, req.multipartErr = parseMultipart(req.Headers, body, req.multipartLimits())
+		req.multipartDone = true
+	}
+
+	return req.multipartForm, req.multipartErr
+}
+
 // Return the value of the specified part if the request is multipart
 func (req *Req) FormValue(key string) string {
 
-	// Get the multipart reader if the request is multipart
-	form, err := parseMultipart(req.Headers, []byte(req.Body))
+	// Get the parsed multipart form if the request is multipart
+	form, err := req.MultipartForm()
 	if err != nil {
 		return ""
 	}
 
-	defer form.RemoveAll()
-
 	// Return the first matching part value
 	values := form.Value[key]
 	if len(values) > 0 {
@@ -201,217 +276,187 @@ func (req *Req) FormValue(key string) string {
 
 // Return the file of the specified part if the request is multipart
 func (req *Req) FormFile(name string) (*FormFile, error) {
-
-	// Get the multipart reader if the request is multipart
-	form, err := parseMultipart(req.Headers, []byte(req.Body))
+	files, err := req.FormFiles(name)
 	if err != nil {
 		return nil, err
 	}
 
-	defer form.RemoveAll()
+	// Return the first matching part file
+	return files[0], nil
+}
+
+// Return all the files of the specified part if the request is multipart
+func (req *Req) FormFiles(name string) ([]*FormFile, error) {
+
+	// Get the parsed multipart form if the request is multipart
+	form, err := req.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
 
-	// Return the first matching part value
 	files := form.File[name]
 	if len(files) == 0 {
 		return nil, fmt.Errorf("file %s not found", name)
 	}
 
-	// Open the file to prepare the bytes we will store in the content field of the
-	// FormFile struct
-	fileHeader := files[0]
-	file, err := fileHeader.Open()
-	if err != nil {
-		return nil, err
-	}
+	return files, nil
+}
 
-	defer file.Close()
+// Release the resources held by the request, like the temporary files of the multipart form
+// This is called automatically when the request finishes
+func (req *Req) cleanup() {
+	if req.multipartForm != nil {
+		if err := req.multipartForm.RemoveAll(); err != nil {
+			log.Println("err removing multipart temp files: ", err)
+		}
+	}
+}
 
-	// Copy the bytes
-	content, err := io.ReadAll(file)
-	if err != nil {
-		return nil, err
+// Return the multipart limits configured on the app, or the defaults if there's no app
+func (req *Req) multipartLimits() multipartLimits {
+	if req.app == nil {
+		return multipartLimits{memory: DefaultMultipartMemory, totalSize: DefaultMaxMultipartSize}
 	}
 
-	return &FormFile{
-		Filename: fileHeader.Filename,
-		Content:  content,
-		Header:   fileHeader.Header,
-	}, nil
+	return multipartLimits{
+		memory:    req.app.MultipartMemory,
+		fileSize:  req.app.MaxFileSize,
+		totalSize: req.app.MaxMultipartSize,
+	}
 }
 
-// Save the multipart form file directly to disk
-// TODO: I think permissions should be a config later
+// Save the multipart form file directly to disk with the default save options
 func (req *Req) Save(formFile *FormFile, destination string) error {
+	_, err := req.SaveFile(formFile, destination, SaveOptions{})
+	return err
+}
+
+// Save the multipart form file to disk and return the path it was saved to
+// The client file name is sanitized first, so it can never escape the destination directory
+// The content is streamed to a temp file in the destination which is then renamed to the
+// final name, so readers never see a partially written file
+func (req *Req) SaveFile(formFile *FormFile, destination string, opts SaveOptions) (string, error) {
 
 	// Check if formFile is nil first
 	if formFile == nil {
-		return fmt.Errorf("nil FormFile")
+		return "", fmt.Errorf("nil FormFile")
 	}
 
-	// Join the file name with the specified destination
-	// TODO: Should be sanitized first
-	fullPath := filepath.Join(destination, formFile.Filename)
+	filename := SanitizeFilename(formFile.Filename)
+	if filename == "" {
+		return "", fmt.Errorf("failed to save file: invalid file name %q", formFile.Filename)
+	}
+
+	perm := opts.Perm
+	if perm == 0 {
+		perm = DefaultFilePerm
+	}
 
 	// Create the directory
 	err := os.MkdirAll(destination, DefaultDirPerm)
 	if err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+		return "", fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Write the file to the destination
-	err = os.WriteFile(fullPath, formFile.Content, DefaultFilePerm)
+	// Open the form file content, whether it's in memory or on disk
+	src, err := formFile.Open()
 	if err != nil {
-		return fmt.Errorf("failed to save file: %w", err)
+		return "", fmt.Errorf("failed to open form file: %w", err)
 	}
 
-	// No errors happened
-	return nil
-}
+	defer src.Close()
 
-// Checks if the specified types are accepted from the HTTP client
-// TODO: Fix Canonicalization
-func (req *Req) Accepts(offered ...string) string {
-	acceptHeader := req.Header("Accept")
+	var content io.Reader = src
 
-	if acceptHeader == "" || len(offered) == 0 {
-		// TODO: Align with RFC 9110 standards
-		if len(offered) != 0 {
-			return offered[0]
-		} else {
-			return ""
+	// Sniff the content type from the first bytes if only some types are allowed
+	if len(opts.AllowedTypes) > 0 {
+		head := make([]byte, 512)
+		n, err := io.ReadFull(src, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", fmt.Errorf("failed to read form file: %w", err)
 		}
-	}
-
-	clientTypes := parseAcceptHeader(acceptHeader)
 
-	for _, clientType := range clientTypes {
-		for _, offered := range offered {
-			if matches(clientType.part, offered) {
-				return offered
-			}
+		contentType := http.DetectContentType(head[:n])
+		if !isTypeAllowed(contentType, opts.AllowedTypes) {
+			return "", fmt.Errorf("%s: %w", contentType, ErrFileTypeNotAllowed)
 		}
-	}
 
-	return ""
-}
-
-// Checks if the specified types are accepted from the HTTP client
-func (req *Req) AcceptsCharsets(offered ...string) string {
-	charsetHeader := req.Header("Accept-Charset")
-	if charsetHeader == "" {
-		// TODO: Align with RFC 2616 standards
-		if len(offered) != 0 {
-			return offered[0]
-		} else {
-			return ""
-		}
+		content = io.MultiReader(bytes.NewReader(head[:n]), src)
 	}
 
-	clientCharsets := parseAcceptHeader(charsetHeader)
-
-	// Handle wildcard
-	for _, cc := range clientCharsets {
-		if cc.part == "*" && cc.q > 0 {
-			return offered[0]
-		}
+	// Stream the content to a temp file next to the destination file
+	tmp, err := os.CreateTemp(destination, ".zttp-upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
 	}
 
-	for _, cc := range clientCharsets {
-		for _, charset := range offered {
-			if strings.EqualFold(cc.part, charset) && cc.q > 0 {
-				return charset
-			}
-		}
+	_, err = io.Copy(tmp, content)
+	if err == nil {
+		err = tmp.Chmod(perm)
 	}
-
-	return ""
-}
-
-func (req *Req) AcceptsEncodings(offered ...string) string {
-	encodingsHeader := req.Header("Accept-Encoding")
-	if encodingsHeader == "" {
-		// TODO: Align with RFC 9110 standards
-		if len(offered) != 0 {
-			return offered[0]
-		} else {
-			return ""
-		}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
 	}
-
-	clientEncodings := parseAcceptHeader(encodingsHeader)
-
-	// Special cases (RFC 7231)
-	for _, enc := range clientEncodings {
-		// "identity" is always acceptable unless explicitly forbidden with q=0
-		if enc.part == "identity" && enc.q == 0 {
-			// Client explicitly refuses identity
-			return ""
-		}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to save file: %w", err)
 	}
 
-	for _, enc := range clientEncodings {
-		for _, offeredEnc := range offered {
-			if strings.EqualFold(enc.part, offeredEnc) && enc.q > 0 {
-				return offeredEnc
-			}
-		}
+	// Move the temp file to its final name
+	fullPath, err := commitUpload(tmp.Name(), destination, filename, opts.OnCollision)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
 	}
 
-	// Check for wildcard
-	for _, enc := range clientEncodings {
-		if enc.part == "*" && enc.q > 0 {
-			return offered[0]
-		}
-	}
+	// No errors happened
+	return fullPath, nil
+}
 
-	return ""
+// Return the offered type the client prefers according to its `Accept` header, or an empty string if none is acceptable
+// Offers can be MIME types like "application/json", or extensions like "json"
+// Every offer is acceptable if the client sent no `Accept` header, so the first one is returned
+func (req *Req) Accepts(offered ...string) string {
+	return req.negotiate("Accept", offered, matchMediaType)
 }
 
-func (req *Req) AcceptsLanguages(offered ...string) string {
-	langHeader := req.Header("Accept-Language")
-	if langHeader == "" {
-		// TODO: Align with RFC 9110 standards
-		if len(offered) != 0 {
-			return offered[0]
-		} else {
-			return ""
-		}
-	}
+// Return the offered charset the client prefers according to its `Accept-Charset` header,
+// or an empty string if none is acceptable
+func (req *Req) AcceptsCharsets(offered ...string) string {
+	return req.negotiate("Accept-Charset", offered, matchToken)
+}
 
-	acceptedLangs := parseAcceptHeader(langHeader)
+// Return the offered content coding the client prefers according to its `Accept-Encoding` header,
+// or an empty string if none is acceptable
+// "identity" is acceptable unless the client excludes it explicitly, with `identity;q=0` or `*;q=0`
+func (req *Req) AcceptsEncodings(offered ...string) string {
+	return req.negotiate("Accept-Encoding", offered, matchToken)
+}
 
-	// Check for wildcard
-	for _, lang := range acceptedLangs {
-		if lang.part == "*" && lang.q > 0 {
-			return offered[0]
-		}
-	}
+// Return the offered language the client prefers according to its `Accept-Language` header,
+// or an empty string if none is acceptable
+// Language ranges match as RFC 4647 describes, so `en` matches an offered `en-US`
+func (req *Req) AcceptsLanguages(offered ...string) string {
+	return req.negotiate("Accept-Language", offered, matchLanguage)
+}
 
-	// Check exact match
-	for _, lang := range acceptedLangs {
-		for _, offeredLang := range offered {
-			if strings.EqualFold(lang.part, offeredLang) && lang.q > 0 {
-				return offeredLang
-			}
-		}
+// Negotiate the offers against the ranges of the passed header
+func (req *Req) negotiate(header string, offered []string, match acceptMatcher) string {
+	if len(offered) == 0 {
+		return ""
 	}
 
-	// Check primary language matches like `en` matches `en-US`
-	for _, lang := range acceptedLangs {
-		if lang.q == 0 {
-			continue
-		}
+	value := req.Header(header)
+	if strings.TrimSpace(value) == "" {
+		return offered[0]
+	}
 
-		primaryLang := strings.Split(lang.part, "-")[0]
-		for _, offeredLang := range offered {
-			offeredPrimary := strings.Split(offeredLang, "-")[0]
-			if strings.EqualFold(primaryLang, offeredPrimary) {
-				return offeredLang
-			}
-		}
+	ranges := parseAcceptRanges(value)
+	if header == "Accept-Encoding" {
+		ranges = withIdentity(ranges)
 	}
 
-	return ""
+	return negotiate(ranges, offered, match)
 }
 
 // TODO: Align with RFC 7239 standards
@@ -493,84 +538,6 @@ func (req *Req) Hostname() string {
 	return strings.ToLower(strings.TrimSpace(host))
 }
 
-// Parse the `Accepts` HTTP client header and return a list of accepted types with their quality factors
-func parseAcceptHeader(header string) []AcceptPart {
-	var items []AcceptPart
-	parts := strings.Split(header, ",")
-
-	for _, part := range parts {
-		trimmed := strings.TrimSpace(part)
-		segments := strings.Split(trimmed, ";")
-
-		mime := segments[0]
-		// Default q-value
-		q := float32(1.0)
-
-		// Parse quality factor if present
-		if len(segments) > 1 && strings.HasPrefix(segments[1], "q=") {
-			fmt.Sscanf(segments[1][2:], "%f", &q)
-		}
-
-		items = append(items, AcceptPart{part: mime, q: q})
-	}
-
-	// Sort the accepted types according to the quality factor from highest to lowest
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].q > items[j].q
-	})
-
-	return items
-}
-
-// Checks for matching between the types of the client header and the specified type
-// TODO: Support case-insensitivity later
-func matches(clientType, offeredType string) bool {
-	// Exact match
-	if clientType == offeredType {
-		return true
-	}
-
-	// Wildcard support
-	if strings.HasSuffix(clientType, "/*") && clientType != "*/*" {
-		return strings.Split(clientType, "/")[0] == strings.Split(offeredType, "/")[0]
-	}
-
-	// Catch-all wildcard
-	return clientType == "*/*"
-}
-
-// Checks if the request is multipart or not and return back the multipart form reader reference
-func parseMultipart(headers map[string]string, body []byte) (*multipart.Form, error) {
-	// Check if it's a multipart request or not
-	contentType := headers["Content-Type"]
-	if contentType == "" {
-		return nil, http.ErrNotMultipart
-	}
-
-	mediaType, params, err := mime.ParseMediaType(contentType)
-	if err != nil {
-		return nil, err
-	}
-
-	if !strings.HasPrefix(mediaType, "multipart/") {
-		return nil, http.ErrNotMultipart
-	}
-
-	// Extract the boundary that separates between different parts
-	boundary := params["boundary"]
-	if boundary == "" {
-		log.Println("no boundary found in Content-Type")
-		return nil, http.ErrNotMultipart
-	}
-
-	// Create a multipart reader from the request body and the parsed boundary
-	reader := multipart.NewReader(bytes.NewReader(body), boundary)
-
-	// 32 MB memory limit + 10 MB added by default
-	// TODO: Should be a configuration later
-	return reader.ReadForm(32 << 20)
-}
-
 // Check if the Cache-Control header contains a valid 'no-cache' directive
 func hasNoCacheDirective(cacheControl string) bool {
 	const directive = "no-cache"
@@ -648,12 +615,19 @@ func isEtagStale(etag string, noneMatchBytes []byte) bool {
 }
 
 // Extract the request line from the buffer of the current client tcp socket
-func extractRequestLine(rdr *bufio.Reader, socket net.Conn) []string {
+func extractRequestLine(rdr *bufio.Reader, socket net.Conn, app *App) []string {
 	var requestParts []string
 
 	// The request line is always the first line in the request
-	requestLine, err := rdr.ReadString('\n')
+	// Its length is limited like header lines, a longer one is most likely a huge URI
+	requestLine, err := readLine(rdr, app.MaxHeaderLineLength)
 	if err != nil {
+		if errors.Is(err, errHeadersTooLarge) {
+			log.Println("request line too long, sending 'URI Too Long' response")
+			sendError(socket, app, 414, closeHeaders())
+			return requestParts
+		}
+
 		if err == io.EOF {
 			log.Println("connection closed by client")
 			return requestParts
@@ -673,7 +647,7 @@ func extractRequestLine(rdr *bufio.Reader, socket net.Conn) []string {
 	// Request line is empty, bad request
 	if requestLine == "" {
 		log.Println("empty request line, sending 'Bad Request' response")
-		sendResponse(socket, []byte("Bad Request"), 400, "text/plain", nil)
+		sendError(socket, app, 400, nil)
 		return requestParts
 	}
 
@@ -681,7 +655,7 @@ func extractRequestLine(rdr *bufio.Reader, socket net.Conn) []string {
 	requestParts = strings.SplitN(requestLine, " ", 3)
 	if len(requestParts) < 2 {
 		log.Println("invalid request line: " + requestLine)
-		sendResponse(socket, []byte("Bad Request"), 400, "text/plain", nil)
+		sendError(socket, app, 400, nil)
 		return requestParts
 	}
 
@@ -689,23 +663,21 @@ func extractRequestLine(rdr *bufio.Reader, socket net.Conn) []string {
 }
 
 // Extract the request headers and the body's content length (if exists) from the buffer of the current client tcp socket
-func extractHeaders(rdr *bufio.Reader) (map[string]string, int) {
+// Zero or negative limits mean no limit on the number of headers or the length of a header line
+func extractHeaders(rdr *bufio.Reader, maxCount, maxLineLength int) (map[string]string, int, error) {
 	headers := make(map[string]string)
 	var contentLength int = 0
+	lengthSeen := false
+	count := 0
 
 	// Keep reading each line and parse it as a header until reaching an empty line
 	for {
-		line, err := rdr.ReadString('\n')
+		line, err := readLine(rdr, maxLineLength)
 		if err != nil {
-			log.Println("err reading headers... " + err.Error())
-			return nil, 0
-		}
-
-		// If the current header is the `Content-Length` header, store its value to return later
-		if strings.HasPrefix(line, "Content-Length") {
-			parts := strings.Split(line, ":")
-			lengthStr := strings.TrimSpace(parts[1])
-			contentLength, err = strconv.Atoi(lengthStr)
+			if !errors.Is(err, errHeadersTooLarge) {
+				log.Println("err reading headers... " + err.Error())
+			}
+			return nil, 0, err
 		}
 
 		// Remove all leading and trailing white spaces and detect the end of the headers section
@@ -714,6 +686,11 @@ func extractHeaders(rdr *bufio.Reader) (map[string]string, int) {
 			break
 		}
 
+		count++
+		if maxCount > 0 && count > maxCount {
+			return nil, 0, errHeadersTooLarge
+		}
+
 		// Parse the header and store it
 		parts := strings.SplitN(line, ": ", 2)
 		if len(parts) != 2 {
@@ -722,32 +699,90 @@ func extractHeaders(rdr *bufio.Reader) (map[string]string, int) {
 
 		key := strings.TrimSpace(parts[0])
 		value := strings.TrimSpace(parts[1])
+
+		// If the current header is the `Content-Length` header, validate its value to return later
+		if strings.EqualFold(key, "Content-Length") {
+			length, err := strconv.Atoi(value)
+			if err != nil || length < 0 {
+				return nil, 0, errInvalidContentLength
+			}
+
+			// Conflicting lengths are a request smuggling attempt, whatever the casing of their keys
+			if lengthSeen && length != contentLength {
+				return nil, 0, errInvalidContentLength
+			}
+
+			contentLength = length
+			lengthSeen = true
+		}
+
 		headers[key] = value
 	}
 
-	return headers, contentLength
+	return headers, contentLength, nil
+}
+
+// Read a single line from the buffer without ever holding more than maxLength bytes of it
+func readLine(rdr *bufio.Reader, maxLength int) (string, error) {
+	var line []byte
+
+	for {
+		chunk, err := rdr.ReadSlice('\n')
+		if maxLength > 0 && len(line)+len(chunk) > maxLength {
+			return "", errHeadersTooLarge
+		}
+
+		line = append(line, chunk...)
+
+		// The line is longer than the buffer, keep reading
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		return string(line), nil
+	}
 }
 
 // Extract the request body from the buffer of the current client tcp socket
+// The body grows with the bytes actually received, instead of trusting the client's `Content-Length`
 func extractBody(rdr *bufio.Reader, contentLength int) string {
 
 	body := ""
 
 	// Read exactly the next `contentLength` bytes in the buffer
 	if contentLength > 0 {
-		bodyBuffer := make([]byte, contentLength)
-		_, err := io.ReadFull(rdr, bodyBuffer)
+		var bodyBuffer strings.Builder
+		_, err := io.CopyN(&bodyBuffer, rdr, int64(contentLength))
 		if err != nil {
 			log.Println("err reading body... " + err.Error())
 			return ""
 		}
 
-		body = string(bodyBuffer)
+		body = bodyBuffer.String()
 	}
 
 	return body
 }
 
+// Read and discard the rest of the body stream so the next request on the connection can be parsed
+// Return false if the body couldn't be drained, or it was too large to be worth draining,
+// in which case the connection should be closed instead
+func drainBody(body io.Reader) bool {
+	n, err := io.CopyN(io.Discard, body, maxBodyDrain+1)
+	if err == io.EOF {
+		return true
+	}
+	if err != nil {
+		log.Println("err draining request body: ", err)
+		return false
+	}
+
+	return n <= maxBodyDrain
+}
+
 // Extract the request queries from the buffer of the current client tcp socket
 func extractQueries(rawPath string) map[string]string {
 	queries := make(map[string]string)
@@ -775,20 +810,5 @@ func extractQueries(rawPath string) map[string]string {
 
 // Extract the request cookies from the request headers
 func extractCookies(headers map[string]string) map[string]string {
-	cookies := make(map[string]string)
-
-	if cookieHeader, ok := headers["Cookie"]; ok {
-		pairs := strings.Split(cookieHeader, ";")
-
-		for _, pair := range pairs {
-			pair = strings.TrimSpace(pair)
-			kv := strings.Split(pair, "=")
-
-			if len(kv) == 2 {
-				cookies[kv[0]] = kv[1]
-			}
-		}
-	}
-
-	return cookies
+	return parseCookieHeader(headers["Cookie"])
 }
@@ -1,8 +1,12 @@
 package zttp
 
 import (
-	"encoding/json"
+	"bytes"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"mime"
 	"net"
@@ -15,200 +19,407 @@ import (
 	"time"
 )
 
-type Cookie struct {
-	Name        string    `json:"name"`
-	Value       string    `json:"value"`
-	Path        string    `json:"path"`
-	Domain      string    `json:"domain"`
-	Expires     time.Time `json:"expires"`
-	MaxAge      int       `json:"max_age"`
-	Secure      bool      `json:"secure"`
-	HttpOnly    bool      `json:"http_only"`
-	SameSite    string    `json:"same_site"`
-	SessionOnly bool      `json:"session_only"`
-}
+var (
+	// Returned when a response body is sent more than once
+	ErrResponseSent = errors.New("response body already sent")
+	// Returned when a response is changed after it was written to the socket
+	ErrResponseCommitted = errors.New("response already committed")
+)
 
+// The response of the current request
+// The status, headers, and body are buffered until the handler and its middlewares return,
+// so middlewares can still change them after calling `next()`
 type Res struct {
 	Socket          net.Conn
 	StatusCode      int
-	Headers         map[string][]string
+	Headers         Header
 	ContentType     string
 	PrettyPrintJSON bool
+	PrettyPrintXML  bool
 	*Ctx
+
+	// The compression negotiated by the compression middleware, if any
+	compression *compression
+	// The ETag generation configured by the ETag middleware, if any
+	etag *etagConfig
+
+	// The buffered body, written to the socket once the response is committed
+	body []byte
+	// If the body was sent, either buffered or streamed
+	sent bool
+	// If the status line and headers were written to the socket
+	committed bool
+	// The body of a chunked response that is still being written, ended once the handler returns
+	openBody io.Closer
 }
 
 // This function sends a text/plain response body
-func (res *Res) Send(data string) {
+// The body is buffered until the response is committed, and sending it twice is an error
+func (res *Res) Send(data string) error {
+	if err := res.checkWritable(); err != nil {
+		return err
+	}
+
 	if res.ContentType == "" {
 		res.ContentType = "text/plain; charset=utf-8"
 	}
 
-	sendResponse(res.Socket, []byte(data), res.StatusCode, res.ContentType, res.Headers)
+	return res.send([]byte(data))
+}
+
+// This function sends a JSON response body, encoded with the app's `JSONEncoder`
+func (res *Res) Json(data any) error {
+	if err := res.checkWritable(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := res.app().jsonEncoder().Encode(&buf, data, res.jsonIndent()); err != nil {
+		log.Println("Error parsing json")
+		return res.sendError(500, "JSON Marshal Failed")
+	}
+
+	res.ContentType = "application/json"
+
+	// Encoders end the value with a newline, which buffered JSON responses never had
+	return res.send(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
 }
 
-// This function sends a JSON response body
-func (res *Res) Json(data any) {
+// This function streams a JSON response body, encoding the data straight to the socket
+// The response is committed right away and sent in chunks, so there's no `Content-Length`,
+// and an error while encoding can only cut the body short, as the status is already sent
+func (res *Res) JsonStream(data any) error {
+	if err := res.checkWritable(); err != nil {
+		return err
+	}
+
+	res.ContentType = "application/json"
+
+	body, err := res.chunkedBody()
+	if err != nil {
+		return err
+	}
+
+	// The body stays open until the handler returns if encoding fails,
+	// so the final chunk is still sent and the connection can be reused
+	res.openBody = body
+
+	if err := res.app().jsonEncoder().Encode(body, data, res.jsonIndent()); err != nil {
+		log.Println("Error streaming json:", err)
+		return err
+	}
+
+	res.openBody = nil
+
+	return body.Close()
+}
+
+// Return the indent of JSON responses, if the app is configured to pretty print them
+func (res *Res) jsonIndent() string {
+	if res.PrettyPrintJSON {
+		return "    "
+	}
+
+	return ""
+}
+
+// Return the app of the response, if it has one
+func (res *Res) app() *App {
+	if req := res.request(); req != nil {
+		return req.app
+	}
+
+	return nil
+}
+
+// This function sends an XML response body, starting with the standard XML declaration
+// An XML content type set before, like `text/xml`, is kept
+func (res *Res) XML(data any) error {
+	if err := res.checkWritable(); err != nil {
+		return err
+	}
+
 	var raw []byte
 	var err error
 
-	// If the app is configured to pretty print JSON responses or not
-	if res.PrettyPrintJSON {
-		raw, err = json.MarshalIndent(data, "", "    ")
+	// If the app is configured to pretty print XML responses or not
+	if res.PrettyPrintXML {
+		raw, err = xml.MarshalIndent(data, "", "    ")
 	} else {
-		raw, err = json.Marshal(data)
+		raw, err = xml.Marshal(data)
 	}
 
 	if err != nil {
-		log.Println("Error parsing json")
-		res.StatusCode = 500
-		res.Send("Internal Server Error: JSON Marshal Failed")
-		return
+		log.Println("Error parsing xml")
+		return res.sendError(500, "XML Marshal Failed")
 	}
 
-	res.ContentType = "application/json"
-	sendResponse(res.Socket, raw, res.StatusCode, res.ContentType, res.Headers)
+	if !strings.Contains(res.ContentType, "xml") {
+		res.ContentType = "application/xml; charset=utf-8"
+	}
+
+	return res.send(append([]byte(xml.Header), raw...))
 }
 
-func (res *Res) Static(path, root string) {
-	// Clean the root directory path
-	root = filepath.Clean(root)
+// Send the data as JSON or XML, whichever the client prefers according to its `Accept` header
+// JSON is sent if the client accepts both equally, and 406 Not Acceptable if it accepts neither
+// Example: res.AutoFormat(user)
+func (res *Res) AutoFormat(data any) error {
+	var err error
+	sendXML := func() { err = res.XML(data) }
+
+	formatErr := res.Format(
+		Formatter{Type: "application/json", Handler: func() { err = res.Json(data) }},
+		Formatter{Type: "application/xml", Handler: sendXML},
+		Formatter{Type: "text/xml", Handler: sendXML},
+	)
 
-	if path == "" {
-		path = "/"
+	if formatErr != nil {
+		return formatErr
 	}
 
-	// Check if file exists
-	fullPath := filepath.Join(root, path)
-	fileInfo, err := os.Stat(fullPath)
+	return err
+}
+
+// Serve the file at the passed path relative to the root directory
+// Directories are served through their index file
+func (res *Res) Static(path, root string, opts ...StaticOptions) {
+	res.StaticFS(path, os.DirFS(root), opts...)
+}
+
+// Serve the file at the passed path of the file system, like an `embed.FS`
+// Directories are served through their index file
+func (res *Res) StaticFS(path string, fsys fs.FS, opts ...StaticOptions) {
+	newStaticServer(fsys, opts).serve(res, path)
+}
+
+// Send the file at the passed path, answering conditional and range requests
+// The content type is detected from the file extension, unless it's already set
+// An `ETag` header set before calling it is used to validate `If-Range` and `If-None-Match`
+func (res *Res) SendFile(filePath string) {
+	file, err := os.Open(filePath)
 	if err != nil {
-		res.Status(404).Send("Not Found")
+		res.sendError(404, "")
 		return
 	}
 
-	// If it's a directory, fallback to index.html
-	if fileInfo.IsDir() {
-		indexPath := filepath.Join(fullPath, "index.html")
-		if _, err := os.Stat(indexPath); err == nil {
-			fullPath = indexPath
-		} else {
-			res.Status(403).Send("Couldn't find index.html in given directory")
-			return
-		}
-	}
+	defer file.Close()
 
-	// Open the file
-	file, err := os.Open(fullPath)
-	if err != nil {
-		res.Status(500).Send("Internal Server Error")
+	fileInfo, err := file.Stat()
+	if err != nil || fileInfo.IsDir() {
+		res.sendError(404, "")
 		return
 	}
 
-	defer file.Close()
+	if res.ContentType == "" {
+		res.ContentType = mime.TypeByExtension(filepath.Ext(filePath))
+	}
 
-	// Get file info again in case we swithced to index.html
-	fileInfo, err = file.Stat()
-	if err != nil {
-		res.Status(500).Send("Internal Server Error")
+	etag := res.Headers.Get("ETag")
+
+	res.Header("Last-Modified", fileInfo.ModTime().UTC().Format(http.TimeFormat))
+	res.serveContent(file, fileInfo.Size(), fileInfo.ModTime(), etag)
+}
+
+// This function streams the response body from the passed reader instead of buffering it
+// The size must be the exact number of bytes the reader will produce
+// Streaming commits the response, so it can't be changed afterwards
+func (res *Res) stream(content io.Reader, size int64) {
+	if err := res.checkWritable(); err != nil {
+		log.Println("Error streaming response body:", err)
 		return
 	}
 
-	// Set content type based on file extension
-	ext := filepath.Ext(fullPath)
-	res.ContentType = mime.TypeByExtension(ext)
+	res.sent = true
+	res.committed = true
+
 	if res.ContentType == "" {
 		res.ContentType = "application/octet-stream"
 	}
-	res.Header("Content-Type", res.ContentType)
 
-	// Set Last-Modified header
-	modTime := fileInfo.ModTime()
-	res.Header("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	// Statuses like 204 and 304 never have a body, so the content is left unread
+	if isBodiless(res.StatusCode) {
+		writeHead(res.Socket, res.StatusCode, 0, res.ContentType, res.Headers)
+		return
+	}
 
-	// Handle If-Modified-Since header
-	ifModifiedSince := ""
-	ifModifiedSinceHeader, ok := res.Headers["If-Modified-Since"]
-	if ok {
-		ifModifiedSince = ifModifiedSinceHeader[0]
+	// The compressed size isn't known before compressing, so the body is sent in chunks
+	if res.shouldCompress(size) {
+		res.streamCompressed(content, size)
+		return
 	}
 
-	if ifModifiedSince != "" {
-		if t, err := time.Parse(http.TimeFormat, ifModifiedSince); err == nil {
-			if modTime.Before(t.Add(1 * time.Second)) {
-				res.Status(304).Send("")
-				return
-			}
-		}
+	writeHead(res.Socket, res.StatusCode, size, res.ContentType, res.Headers)
+
+	if _, err := io.CopyN(res.Socket, content, size); err != nil {
+		log.Println("Error streaming response body:", err)
 	}
+}
 
-	// Read file content
-	content, err := os.ReadFile(fullPath)
+// This function streams the response body compressed with the negotiated encoding
+func (res *Res) streamCompressed(content io.Reader, size int64) {
+	body, err := res.chunkedBody()
 	if err != nil {
-		res.Status(500).Send("Internal Server Error")
+		log.Println("Error compressing response body:", err)
+		return
+	}
+
+	if _, err := io.CopyN(body, content, size); err != nil {
+		log.Println("Error streaming response body:", err)
 		return
 	}
 
-	// Send the file content
-	res.Send(string(content))
+	if err := body.Close(); err != nil {
+		log.Println("Error compressing response body:", err)
+	}
 }
 
-// This function ends the current response
-func (res *Res) End() {
-	if res.ContentType == "" {
-		res.ContentType = "text/plain; charset=utf-8"
+// Commit the response with a body of unknown size sent in chunks, and return the writer of the body
+// The body is compressed if the compression middleware negotiated an encoding
+// Closing the writer ends the body, but not the connection
+func (res *Res) chunkedBody() (io.WriteCloser, error) {
+	res.sent = true
+	res.committed = true
+
+	// Statuses like 204 and 304 never have a body, so whatever is written to it is dropped
+	if isBodiless(res.StatusCode) {
+		writeHead(res.Socket, res.StatusCode, 0, res.ContentType, res.Headers)
+		return discardBody{}, nil
 	}
 
-	sendResponse(res.Socket, []byte(""), res.StatusCode, res.ContentType, res.Headers)
+	chunked := &chunkedWriter{w: res.Socket}
+
+	// The encoder is created before the head is written, so a failure sends the body as is
+	// instead of a head promising an encoding the body never gets
+	var encoder io.WriteCloser
+	if res.shouldCompress(-1) {
+		if w, err := res.compression.newWriter(chunked); err == nil {
+			encoder = w
+			res.markCompressed()
+		}
+	}
+
+	writeHead(res.Socket, res.StatusCode, -1, res.ContentType, res.Headers)
+
+	if encoder == nil {
+		return chunked, nil
+	}
+
+	return &compressedBody{WriteCloser: encoder, chunked: chunked}, nil
 }
 
-// Sets the value of the passed header key
-func (res *Res) Header(key, value string) *Res {
-	if _, exists := res.Headers[key]; !exists {
-		res.Headers[key] = []string{}
+// This function buffers the response body until the response is committed
+func (res *Res) send(body []byte) error {
+	if err := res.checkWritable(); err != nil {
+		return err
 	}
 
-	res.Headers[key] = append(res.Headers[key], value)
+	res.body = body
+	res.sent = true
 
-	return res
+	return nil
 }
 
-// Sets the response cookies
-func (res *Res) SetCookie(cookie Cookie) *Res {
-	// Build the cookie string
-	cookieStr := fmt.Sprintf("%s=%s", cookie.Name, cookie.Value)
+// This function ends the current response
+func (res *Res) End() error {
+	if err := res.checkWritable(); err != nil {
+		return err
+	}
 
-	// Add optional attributes
-	if cookie.Path != "" {
-		cookieStr += fmt.Sprintf("; Path=%s", cookie.Path)
+	if res.ContentType == "" {
+		res.ContentType = "text/plain; charset=utf-8"
 	}
-	if cookie.Domain != "" {
-		cookieStr += fmt.Sprintf("; Domain=%s", cookie.Domain)
+
+	return res.send([]byte(""))
+}
+
+// Write the buffered response to the socket now, instead of once the handler and its middlewares return
+// The response is committed afterwards, so its status, headers, and body can't be changed anymore
+func (res *Res) Flush() error {
+	if res.committed {
+		return nil
 	}
-	if !cookie.Expires.IsZero() {
-		cookieStr += fmt.Sprintf("; Expires=%s", cookie.Expires.UTC().Format(time.RFC1123))
+
+	res.committed = true
+
+	if res.ContentType == "" {
+		res.ContentType = "text/plain; charset=utf-8"
 	}
-	if cookie.MaxAge >= 0 {
-		cookieStr += fmt.Sprintf("; Max-Age=%d", cookie.MaxAge)
+
+	return sendResponse(res.Socket, res.prepareBody(), res.StatusCode, res.ContentType, res.Headers)
+}
+
+// End the body of a chunked response the handler left open, so the client isn't left waiting for it
+func (res *Res) closeOpenBody() {
+	if res.openBody == nil {
+		return
 	}
-	if cookie.Secure {
-		cookieStr += "; Secure"
+
+	if err := res.openBody.Close(); err != nil {
+		log.Println("Error ending response body:", err)
 	}
-	if cookie.HttpOnly {
-		cookieStr += "; HttpOnly"
+
+	res.openBody = nil
+}
+
+// Check if the response was written to the socket
+func (res *Res) IsCommitted() bool {
+	return res.committed
+}
+
+// Return an error if the response body can't be sent anymore
+func (res *Res) checkWritable() error {
+	if res.committed {
+		return ErrResponseCommitted
+	}
+
+	if res.sent {
+		return ErrResponseSent
 	}
-	if cookie.SameSite != "" {
-		switch cookie.SameSite {
-		case "Strict", "Lax", "None":
-			cookieStr += fmt.Sprintf("; SameSite=%s", cookie.SameSite)
-		default:
-			log.Printf("Warning: Invalid SameSite value: %s", cookie.SameSite)
+
+	return nil
+}
+
+// This function applies the compression and the conditional request handling to the buffered body
+// The ETag is generated from the body as sent, so every encoding of it has its own ETag
+func (res *Res) prepareBody() []byte {
+	body := res.body
+
+	if res.shouldCompress(int64(len(body))) {
+		if compressed, err := res.compression.compress(body); err == nil {
+			res.markCompressed()
+			body = compressed
+		} else {
+			log.Println("Error compressing response body:", err)
 		}
 	}
-	if cookie.SessionOnly {
-		cookieStr += "; SessionOnly=true"
+
+	// The client already has this version of the body
+	if res.etag != nil && res.conditionalGet(body) {
+		res.StatusCode = 304
+		body = nil
+	}
+
+	return body
+}
+
+// Adds the value to the passed header key
+func (res *Res) Header(key, value string) *Res {
+	res.Headers.Add(key, value)
+
+	return res
+}
+
+// Sets the response cookies
+// Values are percent-encoded where needed, and invalid cookies are dropped with a warning,
+// as clients would reject them anyway
+func (res *Res) SetCookie(cookie Cookie) *Res {
+	if err := cookie.Valid(); err != nil {
+		log.Printf("Warning: %v", err)
+		return res
 	}
 
-	// Append to headers
-	res.Header("Set-Cookie", cookieStr)
+	res.Header("Set-Cookie", cookie.String())
 
 	return res
 }
@@ -240,7 +451,7 @@ func (res *Res) ClearCookie(key ...string) {
 			Value:   "",
 			Path:    "/",
 			Expires: time.Unix(0, 0),
-			MaxAge:  0,
+			MaxAge:  -1,
 		}
 
 		res.SetCookie(cookie)
@@ -299,6 +510,13 @@ func (res *Res) Vary(fields ...string) {
 // Sets the `Content-Type` HTTP response header to the MIME type specified
 // TODO: Add support for setting the charset
 func (res *Res) Type(contentType string) *Res {
+	res.ContentType = resolveContentType(contentType)
+	return res
+}
+
+// Resolve the passed extension, like `json` or `.json`, to its MIME type
+// Values that are already MIME types are returned as they are
+func resolveContentType(contentType string) string {
 	if strings.HasPrefix(contentType, ".") {
 		contentType = strings.TrimPrefix(contentType, ".")
 	}
@@ -314,8 +532,72 @@ func (res *Res) Type(contentType string) *Res {
 		}
 	}
 
-	res.ContentType = contentType
-	return res
+	return contentType
+}
+
+// A response format of `res.Format()`, sent by the handler if the client accepts its type
+type Formatter struct {
+	// A MIME type like "application/json", an extension like "json", or "default"
+	// The "default" formatter runs when the client accepts none of the others, instead of a 406
+	Type string
+	// Sends the response in this format, the `Content-Type` is already set to the type
+	Handler func()
+}
+
+// Respond in the format the client prefers, according to its `Accept` header and the q-values in it
+// Formats are offered in the passed order, so the first one wins if the client accepts any
+// The response gets `Vary: Accept`, and 406 Not Acceptable if no format matches and there's no default
+// Example:
+//
+//	res.Format(
+//		zttp.Formatter{Type: "json", Handler: func() { res.Json(user) }},
+//		zttp.Formatter{Type: "html", Handler: func() { res.Render("users/show", user) }},
+//	)
+func (res *Res) Format(formats ...Formatter) error {
+	if err := res.checkWritable(); err != nil {
+		return err
+	}
+
+	res.Vary("Accept")
+
+	var fallback *Formatter
+	var offered []string
+	types := make(map[string]*Formatter, len(formats))
+
+	for i := range formats {
+		format := &formats[i]
+		if format.Type == "default" {
+			fallback = format
+			continue
+		}
+
+		// Resolve extensions like `json` the same way `res.Type()` does, and match without the params
+		mediaType, _, _ := strings.Cut(resolveContentType(format.Type), ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+		if _, ok := types[mediaType]; !ok {
+			types[mediaType] = format
+			offered = append(offered, mediaType)
+		}
+	}
+
+	var accepted string
+	if req := res.request(); req != nil && len(offered) > 0 {
+		accepted = req.Accepts(offered...)
+	}
+
+	if format, ok := types[accepted]; ok {
+		res.Type(format.Type)
+		format.Handler()
+		return nil
+	}
+
+	if fallback != nil {
+		fallback.Handler()
+		return nil
+	}
+
+	return res.sendError(406, "")
 }
 
 // Check if a certain string exists in a slice of strings
@@ -323,6 +605,11 @@ func containString(slice []string, target string) bool {
 	return slices.Contains(slice, target)
 }
 
+// Check if responses with the passed status code never have a body
+func isBodiless(code int) bool {
+	return (code >= 100 && code < 200) || code == 204 || code == 304
+}
+
 // Remove duplicates from a slice of strings
 func removeDuplicates(list []string) []string {
 	var result []string
@@ -339,21 +626,13 @@ func removeDuplicates(list []string) []string {
 }
 
 // Writes the response data into the client tcp socket's buffer
-func sendResponse(socket net.Conn, body []byte, code int, contentType string, headers map[string][]string) {
-	statusMessage := http.StatusText(code)
-	fmt.Fprintf(socket, "HTTP/1.1 %d %s\r\n", code, statusMessage)
-	fmt.Fprintf(socket, "Content-Length: %d\r\n", len(body))
-	fmt.Fprintf(socket, "Content-Type: %s\r\n", contentType)
-
-	// If there's any extra response headers
-	if headers != nil {
-		for k, values := range headers {
-			for _, v := range values {
-				fmt.Fprintf(socket, "%s: %s\r\n", k, v)
-			}
-		}
+// The body is dropped for statuses like 204 and 304, which never have one
+func sendResponse(socket net.Conn, body []byte, code int, contentType string, headers Header) error {
+	writeHead(socket, code, int64(len(body)), contentType, headers)
+
+	if isBodiless(code) {
+		return nil
 	}
-	fmt.Fprintf(socket, "\r\n")
 
 	if body == nil {
 		body = []byte{}
@@ -363,4 +642,100 @@ func sendResponse(socket net.Conn, body []byte, code int, contentType string, he
 	if err != nil {
 		log.Println("Error writing response body:", err)
 	}
+
+	return err
+}
+
+// Writes the response status line and headers into the client tcp socket's buffer
+// The content type passed takes precedence over a `Content-Type` header, so it's written only once
+func writeHead(socket net.Conn, code int, contentLength int64, contentType string, headers Header) {
+	statusMessage := http.StatusText(code)
+	fmt.Fprintf(socket, "HTTP/1.1 %d %s\r\n", code, statusMessage)
+
+	// Bodiless responses have no length, and a negative length means the body length
+	// isn't known in advance, so it's sent in chunks
+	if isBodiless(code) {
+		// Nothing to describe
+	} else if contentLength >= 0 {
+		fmt.Fprintf(socket, "Content-Length: %d\r\n", contentLength)
+	} else {
+		fmt.Fprintf(socket, "Transfer-Encoding: chunked\r\n")
+	}
+
+	if contentType == "" {
+		contentType = headers.Get("Content-Type")
+	}
+
+	if contentType != "" {
+		fmt.Fprintf(socket, "Content-Type: %s\r\n", sanitizeHeaderValue(contentType))
+	}
+
+	headers.withDefaults().write(socket)
+	fmt.Fprintf(socket, "\r\n")
+}
+
+// Writes the data into the underlying writer using the chunked transfer coding
+type chunkedWriter struct {
+	w io.Writer
+}
+
+func (cw *chunkedWriter) Write(p []byte) (int, error) {
+	// An empty chunk would end the body
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if _, err := fmt.Fprintf(cw.w, "%x\r\n", len(p)); err != nil {
+		return 0, err
+	}
+
+	if _, err := cw.w.Write(p); err != nil {
+		return 0, err
+	}
+
+	if _, err := io.WriteString(cw.w, "\r\n"); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Writes the last chunk that ends the body
+func (cw *chunkedWriter) Close() error {
+	_, err := io.WriteString(cw.w, "0\r\n\r\n")
+	return err
+}
+
+// The body of a response whose status never has one, dropping whatever is written to it
+type discardBody struct{}
+
+func (discardBody) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (discardBody) Close() error {
+	return nil
+}
+
+// The body of a compressed chunked response, flushing the encoder before the last chunk
+type compressedBody struct {
+	io.WriteCloser
+	chunked *chunkedWriter
+}
+
+// Send the compressed data written so far as a chunk, instead of waiting for more
+func (b *compressedBody) Flush() error {
+	if flusher, ok := b.WriteCloser.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+
+	return nil
+}
+
+func (b *compressedBody) Close() error {
+	if err := b.WriteCloser.Close(); err != nil {
+		return err
+	}
+
+	return b.chunked.Close()
 }
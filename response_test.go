@@ -1,6 +1,7 @@
 package zttp
 
 import (
+	"errors"
 	"slices"
 	"strings"
 	"testing"
@@ -13,6 +14,11 @@ type User struct {
 	Age  int    `json:"age"`
 }
 
+type Order struct {
+	ID   int    `json:"id" xml:"id,attr"`
+	Item string `json:"item" xml:"item"`
+}
+
 // Test sending response
 func TestResponseMethods(t *testing.T) {
 	tests := []struct {
@@ -67,6 +73,7 @@ func TestResponseMethods(t *testing.T) {
 			}
 
 			tt.setup(res)
+			res.Flush()
 			output := string(conn.outBuf)
 
 			for _, s := range tt.contains {
@@ -162,8 +169,12 @@ func TestStaticFileServing(t *testing.T) {
 			res.Static(tt.file, "./examples/static-file-serving/public/")
 			output := string(conn.outBuf)
 
-			if res.Headers["Content-Type"][0] != tt.ctype {
-				t.Errorf("Expected Content-Type %s, got %s", tt.ctype, res.Headers["Content-Type"][0])
+			if res.ContentType != tt.ctype {
+				t.Errorf("Expected Content-Type %s, got %s", tt.ctype, res.ContentType)
+			}
+
+			if strings.Count(output, "Content-Type: ") != 1 {
+				t.Errorf("Expected a single Content-Type header, got: %s", output)
 			}
 
 			if tt.contains != "" && !strings.Contains(output, tt.contains) {
@@ -188,7 +199,8 @@ func TestResponseCookies(t *testing.T) {
 			Secure:      true,
 			HttpOnly:    true,
 			SameSite:    "Lax",
-			SessionOnly: true,
+			Partitioned: true,
+			Priority:    "high",
 		}
 
 		res.SetCookie(cookie)
@@ -202,12 +214,13 @@ func TestResponseCookies(t *testing.T) {
 			"super=cookie",
 			"Path=/",
 			"Domain=example.com",
-			"Expires=Wed, 01 Jan 2025 00:00:00 UTC",
+			"Expires=Wed, 01 Jan 2025 00:00:00 GMT",
 			"Max-Age=86400",
 			"Secure",
 			"HttpOnly",
 			"SameSite=Lax",
-			"SessionOnly=true",
+			"Partitioned",
+			"Priority=High",
 		}
 
 		parts := strings.Split(cookies[0], "; ")
@@ -221,6 +234,59 @@ func TestResponseCookies(t *testing.T) {
 			}
 		}
 	})
+	tests := []struct {
+		name     string
+		cookie   Cookie
+		expected []string
+	}{
+		{
+			name:     "Session cookie",
+			cookie:   Cookie{Name: "cart", Value: "3", Expires: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), MaxAge: 60, SessionOnly: true},
+			expected: []string{"cart=3"},
+		},
+		{
+			name:     "Zero Max-Age is omitted",
+			cookie:   Cookie{Name: "cart", Value: "3", Path: "/"},
+			expected: []string{"cart=3; Path=/"},
+		},
+		{
+			name:     "Negative Max-Age deletes",
+			cookie:   Cookie{Name: "cart", MaxAge: -1},
+			expected: []string{"cart=; Max-Age=0"},
+		},
+		{
+			name:     "Values are encoded",
+			cookie:   Cookie{Name: "prefs", Value: `{"theme": "dark"}`},
+			expected: []string{"prefs={%22theme%22:%20%22dark%22}"},
+		},
+		{
+			name:     "Base64 values are kept",
+			cookie:   Cookie{Name: "token", Value: "YWJjZA=="},
+			expected: []string{"token=YWJjZA=="},
+		},
+		{
+			name:     "Invalid name is dropped",
+			cookie:   Cookie{Name: "bad name", Value: "1"},
+			expected: nil,
+		},
+		{
+			name:     "Partitioned without Secure is dropped",
+			cookie:   Cookie{Name: "embed", Value: "1", Partitioned: true},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &Res{Headers: make(map[string][]string)}
+
+			res.SetCookie(tt.cookie)
+
+			if !slices.Equal(res.Headers["Set-Cookie"], tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, res.Headers["Set-Cookie"])
+			}
+		})
+	}
 }
 
 func TestVaryHeader(t *testing.T) {
@@ -353,7 +419,7 @@ func TestClearCookie(t *testing.T) {
 			},
 			keys: []string{"session"},
 			expectedHeader: []string{
-				"session=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 UTC; Max-Age=0",
+				"session=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0",
 			},
 			description: "Should clear only the specified cookie",
 		},
@@ -364,8 +430,8 @@ func TestClearCookie(t *testing.T) {
 		    },
 		    keys: []string{"session", "token"},
 		    expectedHeader: []string{
-		        "session=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 UTC; Max-Age=0",
-		        "token=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 UTC; Max-Age=0",
+		        "session=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0",
+		        "token=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0",
 		    },
 		    description: "Should clear multiple specified cookies",
 		},
@@ -376,9 +442,9 @@ func TestClearCookie(t *testing.T) {
 		    },
 		    keys: []string{},
 		    expectedHeader: []string{
-		        "session=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 UTC; Max-Age=0",
-		        "prefs=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 UTC; Max-Age=0",
-		        "token=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 UTC; Max-Age=0",
+		        "session=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0",
+		        "prefs=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0",
+		        "token=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0",
 		    },
 		    description: "Should clear all cookies when no keys are provided",
 		},
@@ -398,7 +464,7 @@ func TestClearCookie(t *testing.T) {
 		    },
 		    keys: []string{"nonexistent"},
 		    expectedHeader: []string{
-		        "nonexistent=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 UTC; Max-Age=0",
+		        "nonexistent=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0",
 		    },
 		    description: "Should still set header for non-existent cookies",
 		},
@@ -417,7 +483,7 @@ func TestClearCookie(t *testing.T) {
 		//     },
 		//     keys: []string{"admin"},
 		//     expectedHeader: []string{
-		//         "admin=; Path=/admin; Expires=Thu, 01 Jan 1970 00:00:00 UTC; Max-Age=0",
+		//         "admin=; Path=/admin; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0",
 		//     },
 		//     description: "Should respect original cookie path when clearing",
 		// },
@@ -457,3 +523,276 @@ func TestClearCookie(t *testing.T) {
 		})
 	}
 }
+
+// Test buffering the response until it's committed
+func TestResponseCommit(t *testing.T) {
+	conn := &MockConn{}
+	res := &Res{
+		Socket:     conn,
+		StatusCode: 200,
+		Headers:    make(map[string][]string),
+	}
+
+	if err := res.Send("first"); err != nil {
+		t.Fatalf("Expected the first send to succeed, got %v", err)
+	}
+
+	if len(conn.outBuf) != 0 || res.IsCommitted() {
+		t.Fatalf("Expected the response to be buffered, got: %s", conn.outBuf)
+	}
+
+	if err := res.Send("second"); !errors.Is(err, ErrResponseSent) {
+		t.Errorf("Expected ErrResponseSent for a second send, got %v", err)
+	}
+
+	if err := res.Json(map[string]string{"third": "true"}); !errors.Is(err, ErrResponseSent) {
+		t.Errorf("Expected ErrResponseSent for a second JSON send, got %v", err)
+	}
+
+	// The status and headers can still change until the response is committed
+	res.Status(201).Header("X-Late", "true")
+
+	if err := res.Flush(); err != nil {
+		t.Fatalf("Expected flushing to succeed, got %v", err)
+	}
+
+	output := string(conn.outBuf)
+	if !strings.HasPrefix(output, "HTTP/1.1 201 Created") || !strings.Contains(output, "X-Late: true") ||
+		!strings.HasSuffix(output, "\r\n\r\nfirst") {
+		t.Errorf("Expected the first body with the late changes, got: %s", output)
+	}
+
+	if !res.IsCommitted() {
+		t.Error("Expected the response to be committed after flushing")
+	}
+
+	if err := res.End(); !errors.Is(err, ErrResponseCommitted) {
+		t.Errorf("Expected ErrResponseCommitted after flushing, got %v", err)
+	}
+
+	// Flushing twice writes nothing
+	res.Flush()
+	if string(conn.outBuf) != output {
+		t.Errorf("Expected nothing written after the response was committed, got: %s", conn.outBuf)
+	}
+}
+
+// Test dropping the body of statuses that never have one
+func TestBodilessResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		write  func(res *Res)
+	}{
+		{
+			name:   "Buffered 204",
+			status: 204,
+			write:  func(res *Res) { res.Send("hello") },
+		},
+		{
+			name:   "Buffered 304",
+			status: 304,
+			write:  func(res *Res) { res.Send("hello") },
+		},
+		{
+			name:   "Streamed 204",
+			status: 204,
+			write:  func(res *Res) { res.stream(strings.NewReader("hello"), 5) },
+		},
+		{
+			name:   "Chunked 204",
+			status: 204,
+			write:  func(res *Res) { res.JsonStream(map[string]string{"hello": "world"}) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &MockConn{}
+			res := &Res{
+				Socket:     conn,
+				StatusCode: tt.status,
+				Headers:    make(map[string][]string),
+			}
+
+			tt.write(res)
+			res.Flush()
+
+			output := string(conn.outBuf)
+			if _, body, _ := strings.Cut(output, "\r\n\r\n"); body != "" {
+				t.Errorf("Expected no body, got: %q", output)
+			}
+
+			if strings.Contains(output, "Content-Length") || strings.Contains(output, "Transfer-Encoding") {
+				t.Errorf("Expected no body framing headers, got: %q", output)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	app := NewApp()
+
+	app.Get("/user", func(req *Req, res *Res) {
+		res.Format(
+			Formatter{Type: "application/json", Handler: func() { res.Json(map[string]string{"name": "zttp"}) }},
+			Formatter{Type: "text/html", Handler: func() { res.Send("<p>zttp</p>") }},
+			Formatter{Type: "text/csv", Handler: func() { res.Send("name\nzttp") }},
+		)
+	})
+	app.Get("/fallback", func(req *Req, res *Res) {
+		res.Format(
+			Formatter{Type: "application/json", Handler: func() { res.Json("zttp") }},
+			Formatter{Type: "default", Handler: func() { res.Type("text/plain").Send("zttp") }},
+		)
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		accept         string
+		expectedStatus string
+		expectedType   string
+	}{
+		{"No Accept header", "/user", "", "HTTP/1.1 200 OK", "application/json"},
+		{"Exact type", "/user", "text/html", "HTTP/1.1 200 OK", "text/html"},
+		{"Highest q-value", "/user", "application/json;q=0.5, text/csv;q=0.9", "HTTP/1.1 200 OK", "text/csv"},
+		{"Equal q-values keep the client order", "/user", "text/csv, text/html", "HTTP/1.1 200 OK", "text/csv"},
+		{"Wildcard subtype", "/user", "text/*", "HTTP/1.1 200 OK", "text/html"},
+		{"Any type", "/user", "*/*", "HTTP/1.1 200 OK", "application/json"},
+		{"Nothing matches", "/user", "image/png", "HTTP/1.1 406 Not Acceptable", "text/plain; charset=utf-8"},
+		{"Default format", "/fallback", "image/png", "HTTP/1.1 200 OK", "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.accept != "" {
+				headers["Accept"] = tt.accept
+			}
+
+			response := mockRequest(app, "GET", tt.path, "", headers)
+
+			if !strings.HasPrefix(response, tt.expectedStatus) {
+				t.Errorf("Expected status %q, got: %s", tt.expectedStatus, response)
+			}
+
+			if contentType := responseHeader(response, "Content-Type"); contentType != tt.expectedType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.expectedType, contentType)
+			}
+
+			if vary := responseHeader(response, "Vary"); vary != "Accept" {
+				t.Errorf("Expected Vary: Accept, got %q", vary)
+			}
+		})
+	}
+}
+
+func TestXML(t *testing.T) {
+	tests := []struct {
+		name     string
+		pretty   bool
+		setup    func(*Res)
+		contains []string
+	}{
+		{
+			name:  "Compact",
+			setup: func(r *Res) { r.XML(Order{ID: 7, Item: "book"}) },
+			contains: []string{
+				"Content-Type: application/xml; charset=utf-8",
+				`<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<Order id="7"><item>book</item></Order>`,
+			},
+		},
+		{
+			name:   "Pretty printed",
+			pretty: true,
+			setup:  func(r *Res) { r.XML(Order{ID: 7, Item: "book"}) },
+			contains: []string{
+				"<Order id=\"7\">\n    <item>book</item>\n</Order>",
+			},
+		},
+		{
+			name:  "XML content type kept",
+			setup: func(r *Res) { r.Type("text/xml").XML(Order{ID: 7}) },
+			contains: []string{
+				"Content-Type: text/xml\r\n",
+			},
+		},
+		{
+			name:  "Marshal failure",
+			setup: func(r *Res) { r.XML(map[string]string{"unsupported": "map"}) },
+			contains: []string{
+				"HTTP/1.1 500 Internal Server Error",
+				"Internal Server Error: XML Marshal Failed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &MockConn{}
+			res := &Res{
+				Socket:         conn,
+				StatusCode:     200,
+				Headers:        make(Header),
+				PrettyPrintXML: tt.pretty,
+			}
+
+			tt.setup(res)
+			res.Flush()
+			output := string(conn.outBuf)
+
+			for _, s := range tt.contains {
+				if !strings.Contains(output, s) {
+					t.Errorf("Expected response to contain %q, got: %s", s, output)
+				}
+			}
+		})
+	}
+}
+
+func TestAutoFormat(t *testing.T) {
+	app := NewApp()
+
+	app.Get("/order", func(req *Req, res *Res) {
+		res.AutoFormat(Order{ID: 7, Item: "book"})
+	})
+
+	tests := []struct {
+		name           string
+		accept         string
+		expectedStatus string
+		expectedType   string
+		expectedBody   string
+	}{
+		{"JSON by default", "", "HTTP/1.1 200 OK", "application/json", `{"id":7,"item":"book"}`},
+		{"JSON on ties", "*/*", "HTTP/1.1 200 OK", "application/json", `{"id":7,"item":"book"}`},
+		{"Application XML", "application/xml", "HTTP/1.1 200 OK", "application/xml", `<Order id="7"><item>book</item></Order>`},
+		{"Text XML", "text/xml", "HTTP/1.1 200 OK", "text/xml", `<Order id="7"><item>book</item></Order>`},
+		{"Preferred XML", "application/json;q=0.5, application/xml", "HTTP/1.1 200 OK", "application/xml", `<Order id="7"><item>book</item></Order>`},
+		{"Neither", "text/html", "HTTP/1.1 406 Not Acceptable", "text/plain; charset=utf-8", "Not Acceptable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.accept != "" {
+				headers["Accept"] = tt.accept
+			}
+
+			response := mockRequest(app, "GET", "/order", "", headers)
+
+			if !strings.HasPrefix(response, tt.expectedStatus) {
+				t.Errorf("Expected status %q, got: %s", tt.expectedStatus, response)
+			}
+
+			if contentType := responseHeader(response, "Content-Type"); contentType != tt.expectedType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.expectedType, contentType)
+			}
+
+			if !strings.Contains(response, tt.expectedBody) {
+				t.Errorf("Expected body %q, got: %s", tt.expectedBody, response)
+			}
+		})
+	}
+}
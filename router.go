@@ -1,8 +1,9 @@
 package zttp
 
 import (
+	"fmt"
 	"log"
-	"net"
+	"net/url"
 	"path"
 	"strings"
 )
@@ -12,67 +13,157 @@ type Handler func(req *Req, res *Res)
 type Route struct {
 	path    string
 	handler Handler
+
+	// Max request body size of this route, zero means the app's `BodyLimit`
+	// and a negative value means no limit at all
+	bodyLimit int64
+
+	// The name of the route, for building its URL with `app.URL()` and `res.RedirectToRoute()`
+	name string
 }
 
 type Router struct {
 	*App
 	prefix       string
-	getRoutes    []Route
-	postRoutes   []Route
-	deleteRoutes []Route
-	putRoutes    []Route
-	patchRoutes  []Route
+	getRoutes    []*Route
+	postRoutes   []*Route
+	deleteRoutes []*Route
+	putRoutes    []*Route
+	patchRoutes  []*Route
 	middlewares  []MiddlewareWrapper
 }
 
 // Register the passed handler and path with the app's get routes
-func (app *App) Get(path string, handler Handler) {
-	app.getRoutes = append(app.getRoutes, Route{path, applyMiddleware(handler, app.Router)})
+func (app *App) Get(path string, handler Handler) *Route {
+	route := &Route{path: path, handler: applyMiddleware(handler, app.Router)}
+	app.getRoutes = append(app.getRoutes, route)
+	return route
 }
 
 // Register the passed handler and path with the app's delete routes
-func (app *App) Delete(path string, handler Handler) {
-	app.deleteRoutes = append(app.deleteRoutes, Route{path, applyMiddleware(handler, app.Router)})
+func (app *App) Delete(path string, handler Handler) *Route {
+	route := &Route{path: path, handler: applyMiddleware(handler, app.Router)}
+	app.deleteRoutes = append(app.deleteRoutes, route)
+	return route
 }
 
 // Register the passed handler and path with the app's post routes
-func (app *App) Post(path string, handler Handler) {
-	app.postRoutes = append(app.postRoutes, Route{path, applyMiddleware(handler, app.Router)})
+func (app *App) Post(path string, handler Handler) *Route {
+	route := &Route{path: path, handler: applyMiddleware(handler, app.Router)}
+	app.postRoutes = append(app.postRoutes, route)
+	return route
 }
 
 // Register the passed handler and path with the app's put routes
-func (app *App) Put(path string, handler Handler) {
-	app.putRoutes = append(app.putRoutes, Route{path, applyMiddleware(handler, app.Router)})
+func (app *App) Put(path string, handler Handler) *Route {
+	route := &Route{path: path, handler: applyMiddleware(handler, app.Router)}
+	app.putRoutes = append(app.putRoutes, route)
+	return route
 }
 
 // Register the passed handler and path with the app's patch routes
-func (app *App) Patch(path string, handler Handler) {
-	app.patchRoutes = append(app.patchRoutes, Route{path, applyMiddleware(handler, app.Router)})
+func (app *App) Patch(path string, handler Handler) *Route {
+	route := &Route{path: path, handler: applyMiddleware(handler, app.Router)}
+	app.patchRoutes = append(app.patchRoutes, route)
+	return route
 }
 
 // Register the passed handler and path with the router's get routes
-func (router *Router) Get(path string, handler Handler) {
-	router.getRoutes = append(router.getRoutes, Route{cleanPath(router.prefix, path), applyMiddleware(handler, router)})
+func (router *Router) Get(path string, handler Handler) *Route {
+	route := &Route{path: cleanPath(router.prefix, path), handler: applyMiddleware(handler, router)}
+	router.getRoutes = append(router.getRoutes, route)
+	return route
 }
 
 // Register the passed handler and path with the router's delete routes
-func (router *Router) Delete(path string, handler Handler) {
-	router.deleteRoutes = append(router.deleteRoutes, Route{cleanPath(router.prefix, path), applyMiddleware(handler, router)})
+func (router *Router) Delete(path string, handler Handler) *Route {
+	route := &Route{path: cleanPath(router.prefix, path), handler: applyMiddleware(handler, router)}
+	router.deleteRoutes = append(router.deleteRoutes, route)
+	return route
 }
 
 // Register the passed handler and path with the router's post routes
-func (router *Router) Post(path string, handler Handler) {
-	router.postRoutes = append(router.postRoutes, Route{cleanPath(router.prefix, path), applyMiddleware(handler, router)})
+func (router *Router) Post(path string, handler Handler) *Route {
+	route := &Route{path: cleanPath(router.prefix, path), handler: applyMiddleware(handler, router)}
+	router.postRoutes = append(router.postRoutes, route)
+	return route
 }
 
 // Register the passed handler and path with the router's put routes
-func (router *Router) Put(path string, handler Handler) {
-	router.putRoutes = append(router.putRoutes, Route{cleanPath(router.prefix, path), applyMiddleware(handler, router)})
+func (router *Router) Put(path string, handler Handler) *Route {
+	route := &Route{path: cleanPath(router.prefix, path), handler: applyMiddleware(handler, router)}
+	router.putRoutes = append(router.putRoutes, route)
+	return route
 }
 
 // Register the passed handler and path with the router's patch routes
-func (router *Router) Patch(path string, handler Handler) {
-	router.patchRoutes = append(router.patchRoutes, Route{cleanPath(router.prefix, path), applyMiddleware(handler, router)})
+func (router *Router) Patch(path string, handler Handler) *Route {
+	route := &Route{path: cleanPath(router.prefix, path), handler: applyMiddleware(handler, router)}
+	router.patchRoutes = append(router.patchRoutes, route)
+	return route
+}
+
+// Set the max request body size of the route, overriding the app's `BodyLimit`
+// Requests declaring a larger body are rejected with 413 before reading it
+// A negative limit disables the limit for this route
+func (route *Route) BodyLimit(limit int64) *Route {
+	route.bodyLimit = limit
+	return route
+}
+
+// Name the route, so its URL can be built from its name with `app.URL()`
+// Example: app.Get("/users/:id", handler).Name("user")
+func (route *Route) Name(name string) *Route {
+	route.name = name
+	return route
+}
+
+// Build the path of the named route, filling its params with the passed values
+// The values are escaped, except for the `*` param of a catch-all route, whose segments are escaped one by one
+// Example: app.URL("user", map[string]string{"id": "7"}) returns "/users/7"
+func (app *App) URL(name string, params map[string]string) (string, error) {
+	route := app.namedRoute(name)
+	if route == nil {
+		return "", fmt.Errorf("no route named %q", name)
+	}
+
+	parts := strings.Split(route.path, "/")
+	for i, part := range parts {
+		if part == "*" && i == len(parts)-1 {
+			segments := strings.Split(params["*"], "/")
+			for j, segment := range segments {
+				segments[j] = url.PathEscape(segment)
+			}
+			parts[i] = strings.Join(segments, "/")
+		} else if strings.HasPrefix(part, ":") {
+			value, ok := params[part[1:]]
+			if !ok || value == "" {
+				return "", fmt.Errorf("missing param %q of route %q", part[1:], name)
+			}
+			parts[i] = url.PathEscape(value)
+		}
+	}
+
+	return strings.Join(parts, "/"), nil
+}
+
+// Find the route with the passed name among the routes of all the routers
+func (app *App) namedRoute(name string) *Route {
+	if name == "" {
+		return nil
+	}
+
+	for _, router := range app.Routers {
+		for _, routes := range [][]*Route{router.getRoutes, router.postRoutes, router.putRoutes, router.patchRoutes, router.deleteRoutes} {
+			for _, route := range routes {
+				if route.name == name {
+					return route
+				}
+			}
+		}
+	}
+
+	return nil
 }
 
 func cleanPath(prefix, p string) string {
@@ -98,10 +189,19 @@ func cleanPath(prefix, p string) string {
 	return full
 }
 
-// Find the matched handler with the passed path from the router and parse params, if exist
-func findHandler(method, path string, socket net.Conn, app *App) (Handler, map[string]string) {
+// The methods routes can be registered for, sent in the `Allow` header of 405 responses
+const allowedMethods = "GET, POST, PUT, PATCH, DELETE"
+
+// Find the matched route with the passed path from the router and parse params, if exist
+// Literal and param routes of every router take precedence over catch-all routes,
+// so a catch-all like `app.Static("/", dir)` doesn't shadow the routes registered after it
+// The returned bool is false if no route can be registered for the method at all
+func findHandler(method, path string, app *App) (*Route, map[string]string, bool) {
+	var catchAll *Route
+	var catchAllParams map[string]string
+
 	for _, router := range app.Routers {
-		var routes []Route
+		var routes []*Route
 		switch method {
 		case "GET":
 			routes = router.getRoutes
@@ -115,52 +215,94 @@ func findHandler(method, path string, socket net.Conn, app *App) (Handler, map[s
 			routes = router.patchRoutes
 		default:
 			log.Println("unsupported method:", method)
-			sendResponse(socket, []byte("Method Not Allowed"), 405, "text/plain", nil)
-			return nil, nil
+			return nil, nil, false
+		}
+
+		route, params := matchRoute(path, routes)
+		if route == nil {
+			continue
+		}
+
+		if !route.isCatchAll() {
+			return route, params, true
 		}
 
-		if handler, params := matchRoute(path, routes); handler != nil {
-			return handler, params
+		if catchAll == nil || route.depth() > catchAll.depth() {
+			catchAll, catchAllParams = route, params
 		}
 	}
 
-	return nil, nil
+	return catchAll, catchAllParams, true
 }
 
-// This function searches for the matching handler for the passed request path
+// This function searches for the matching route for the passed request path
 // As well as extracting the params, if exist
-func matchRoute(requestPath string, routes []Route) (Handler, map[string]string) {
+// The first matching literal or param route wins, otherwise the catch-all route with the longest prefix does
+func matchRoute(requestPath string, routes []*Route) (*Route, map[string]string) {
+	var catchAll *Route
+	var catchAllParams map[string]string
+
 	for _, route := range routes {
-		params := make(map[string]string)
+		params, ok := route.match(requestPath)
+		if !ok {
+			continue
+		}
 
-		// Split both route and request paths with `/` delimiter to compare each part respectively
-		routeParts := strings.Split(route.path, "/")
-		requestParts := strings.Split(requestPath, "/")
+		if !route.isCatchAll() {
+			return route, params
+		}
 
-		// if the lengths don't match, we don't have to compare to know they don't match
-		if len(routeParts) != len(requestParts) {
-			continue
+		if catchAll == nil || route.depth() > catchAll.depth() {
+			catchAll, catchAllParams = route, params
 		}
+	}
 
-		match := true
-		for i := range routeParts {
-			// If it's a param, parse it
-			// Otherwise, check for equality
-			if strings.HasPrefix(routeParts[i], ":") {
-				paramName := routeParts[i][1:]
-				params[paramName] = requestParts[i]
-			} else if routeParts[i] != requestParts[i] {
-				match = false
-				break
-			}
+	return catchAll, catchAllParams
+}
+
+// Check if the route ends with a `*` catch-all part
+func (route *Route) isCatchAll() bool {
+	return strings.HasSuffix(route.path, "/*") || route.path == "*"
+}
+
+// Return the number of parts of the route path, deeper catch-all routes are more specific
+func (route *Route) depth() int {
+	return strings.Count(route.path, "/")
+}
+
+// Check if the route matches the passed request path, and extract its params
+func (route *Route) match(requestPath string) (map[string]string, bool) {
+	params := make(map[string]string)
+
+	// Split both route and request paths with `/` delimiter to compare each part respectively
+	routeParts := strings.Split(route.path, "/")
+	requestParts := strings.Split(requestPath, "/")
+
+	// A trailing `*` part is a catch-all that matches the rest of the request path, even if empty
+	catchAll := routeParts[len(routeParts)-1] == "*"
+
+	// if the lengths don't match, we don't have to compare to know they don't match
+	if catchAll {
+		if len(requestParts) < len(routeParts)-1 {
+			return nil, false
 		}
+	} else if len(routeParts) != len(requestParts) {
+		return nil, false
+	}
 
-		// If matched, return it
-		if match {
-			return route.handler, params
+	for i := range routeParts {
+		// If it's the catch-all, store the rest of the path as the `*` param
+		// If it's a param, parse it
+		// Otherwise, check for equality
+		if catchAll && i == len(routeParts)-1 {
+			params["*"] = strings.Join(requestParts[i:], "/")
+		} else if strings.HasPrefix(routeParts[i], ":") {
+			paramName := routeParts[i][1:]
+			params[paramName] = requestParts[i]
+		} else if routeParts[i] != requestParts[i] {
+			return nil, false
 		}
 	}
 
-	// Default: no match
-	return nil, nil
+	return params, true
 }
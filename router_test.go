@@ -78,7 +78,7 @@ func TestRouteMatching(t *testing.T) {
 				app.Patch(tt.path, tt.handler)
 			}
 
-			response := mockRequest(app, tt.method, tt.path, "")
+			response := mockRequest(app, tt.method, tt.path, "", nil)
 			if !strings.Contains(response, tt.expected) {
 				t.Errorf("Expected response to contain '%s', but got '%s'", tt.expected, response)
 			}
@@ -128,7 +128,7 @@ func TestDynamicRouting(t *testing.T) {
 			app := NewApp()
 			tt.setup(app)
 
-			response := mockRequest(app, tt.method, tt.path, "")
+			response := mockRequest(app, tt.method, tt.path, "", nil)
 
 			if !strings.Contains(response, tt.expected) {
 				t.Errorf("Expected response to contain '%s', but got '%s'", tt.expected, response)
@@ -141,11 +141,23 @@ func TestDynamicRouting(t *testing.T) {
 func TestNotFoundHandler(t *testing.T) {
 	t.Run("Non-existent route", func(t *testing.T) {
 		app := NewApp()
-		response := mockRequest(app, "GET", "/nonexistent", "")
+		response := mockRequest(app, "GET", "/nonexistent", "", nil)
 		if !strings.Contains(response, "Not Found") {
 			t.Errorf("Expected 'Not Found', but got '%s'", response)
 		}
 	})
+
+	t.Run("Unsupported method", func(t *testing.T) {
+		app := NewApp()
+		response := mockRequest(app, "TRACE", "/nonexistent", "", nil)
+		if !strings.HasPrefix(response, "HTTP/1.1 405 Method Not Allowed") || strings.Count(response, "HTTP/1.1 ") != 1 {
+			t.Errorf("Expected a single 405 response, but got '%s'", response)
+		}
+
+		if !strings.Contains(response, "Allow: GET, POST, PUT, PATCH, DELETE") {
+			t.Errorf("Expected the Allow header, but got '%s'", response)
+		}
+	})
 }
 
 // Test creating a custom router
@@ -188,7 +200,7 @@ func TestCustomRouter(t *testing.T) {
 			router := app.NewRouter("/api/v1")
 
 			tt.setup(router)
-			response := mockRequest(app, tt.method, tt.path, "")
+			response := mockRequest(app, tt.method, tt.path, "", nil)
 			if !strings.Contains(response, tt.expected) {
 				t.Errorf("Expected '%s', but got '%s'", tt.expected, response)
 			}
@@ -196,6 +208,81 @@ func TestCustomRouter(t *testing.T) {
 	}
 }
 
+// Test catch-all routes
+func TestCatchAllRouting(t *testing.T) {
+	app := NewApp()
+
+	app.Get("/files/*", func(req *Req, res *Res) {
+		res.Send("files: " + req.Param("*"))
+	})
+
+	router := app.NewRouter("/api")
+	router.Get("/docs/*", func(req *Req, res *Res) {
+		res.Send("docs: " + req.Param("*"))
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"Nested path", "/files/a/b/c.txt", "files: a/b/c.txt"},
+		{"Single part", "/files/c.txt", "files: c.txt"},
+		{"Empty rest", "/files", "files: "},
+		{"Router catch-all", "/api/docs/v1/intro", "docs: v1/intro"},
+		{"Prefix mismatch", "/filesystem", "Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := mockRequest(app, "GET", tt.path, "", nil)
+			if !strings.HasSuffix(response, tt.expected) {
+				t.Errorf("Expected response to end with '%s', but got '%s'", tt.expected, response)
+			}
+		})
+	}
+}
+
+// Test that literal and param routes take precedence over catch-alls registered before them
+func TestCatchAllPriority(t *testing.T) {
+	app := NewApp()
+
+	app.Get("/*", func(req *Req, res *Res) {
+		res.Send("root: " + req.Param("*"))
+	})
+	app.Get("/assets/*", func(req *Req, res *Res) {
+		res.Send("assets: " + req.Param("*"))
+	})
+	app.Get("/api/users", func(req *Req, res *Res) {
+		res.Send("all users")
+	})
+
+	router := app.NewRouter("/api")
+	router.Get("/users/:id", func(req *Req, res *Res) {
+		res.Send("user " + req.Param("id"))
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"Literal route after a catch-all", "/api/users", "all users"},
+		{"Param route of another router", "/api/users/7", "user 7"},
+		{"Deeper catch-all wins", "/assets/app.js", "assets: app.js"},
+		{"Root catch-all", "/about/team", "root: about/team"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := mockRequest(app, "GET", tt.path, "", nil)
+			if !strings.HasSuffix(response, tt.expected) {
+				t.Errorf("Expected response to end with '%s', but got '%s'", tt.expected, response)
+			}
+		})
+	}
+}
+
 // Test path cleaning logic
 func TestCleanPath(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,166 @@
+package zttp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var (
+	// Returned when the app has no keys for signed or encrypted cookies
+	ErrNoCookieKeys = errors.New("no cookie keys configured")
+	// Returned when the request has no cookie with the passed name
+	ErrCookieNotFound = errors.New("cookie not found")
+	// Returned when a signed or encrypted cookie was tampered with, or made with a key the app doesn't have
+	ErrInvalidCookieValue = errors.New("invalid signed or encrypted cookie value")
+)
+
+// Cookie values are base64 so any value fits in a cookie, and the signature follows the last dot
+var cookieEncoding = base64.RawURLEncoding
+
+// Set a cookie signed with HMAC-SHA256 by the first key of the app's `CookieSigningKeys`
+// The value stays readable by the client, but can't be changed without `req.SignedCookie()` rejecting it
+// The signature covers the name too, so the value can't be moved to another cookie
+// Example: res.SetSignedCookie(zttp.Cookie{Name: "uid", Value: "42", HttpOnly: true})
+func (res *Res) SetSignedCookie(cookie Cookie) error {
+	app := res.app()
+	if app == nil || len(app.CookieSigningKeys) == 0 {
+		return ErrNoCookieKeys
+	}
+
+	if err := cookie.Valid(); err != nil {
+		return err
+	}
+
+	value := cookieEncoding.EncodeToString([]byte(cookie.Value))
+	cookie.Value = value + "." + cookieEncoding.EncodeToString(signCookie(app.CookieSigningKeys[0], cookie.Name, value))
+
+	res.SetCookie(cookie)
+
+	return nil
+}
+
+// Return the value of the signed cookie, verified against every key of the app's `CookieSigningKeys`,
+// so cookies signed before a key rotation are still accepted
+func (req *Req) SignedCookie(name string) (string, error) {
+	if req.app == nil || len(req.app.CookieSigningKeys) == 0 {
+		return "", ErrNoCookieKeys
+	}
+
+	raw, ok := req.Cookies[name]
+	if !ok {
+		return "", ErrCookieNotFound
+	}
+
+	i := strings.LastIndexByte(raw, '.')
+	if i < 0 {
+		return "", ErrInvalidCookieValue
+	}
+
+	signature, err := cookieEncoding.DecodeString(raw[i+1:])
+	if err != nil {
+		return "", ErrInvalidCookieValue
+	}
+
+	for _, key := range req.app.CookieSigningKeys {
+		if hmac.Equal(signature, signCookie(key, name, raw[:i])) {
+			value, err := cookieEncoding.DecodeString(raw[:i])
+			if err != nil {
+				return "", ErrInvalidCookieValue
+			}
+			return string(value), nil
+		}
+	}
+
+	return "", ErrInvalidCookieValue
+}
+
+// Set a cookie encrypted with AES-GCM by the first key of the app's `CookieEncryptionKeys`
+// The client can neither read nor change the value, and the name is authenticated with it
+// Example: res.SetEncryptedCookie(zttp.Cookie{Name: "hint", Value: "beta-user", HttpOnly: true})
+func (res *Res) SetEncryptedCookie(cookie Cookie) error {
+	app := res.app()
+	if app == nil || len(app.CookieEncryptionKeys) == 0 {
+		return ErrNoCookieKeys
+	}
+
+	if err := cookie.Valid(); err != nil {
+		return err
+	}
+
+	aead, err := cookieCipher(app.CookieEncryptionKeys[0])
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(cookie.Value), []byte(cookie.Name))
+	cookie.Value = cookieEncoding.EncodeToString(sealed)
+
+	res.SetCookie(cookie)
+
+	return nil
+}
+
+// Return the decrypted value of the encrypted cookie, trying every key of the app's `CookieEncryptionKeys`,
+// so cookies encrypted before a key rotation can still be read
+func (req *Req) EncryptedCookie(name string) (string, error) {
+	if req.app == nil || len(req.app.CookieEncryptionKeys) == 0 {
+		return "", ErrNoCookieKeys
+	}
+
+	raw, ok := req.Cookies[name]
+	if !ok {
+		return "", ErrCookieNotFound
+	}
+
+	sealed, err := cookieEncoding.DecodeString(raw)
+	if err != nil {
+		return "", ErrInvalidCookieValue
+	}
+
+	for _, key := range req.app.CookieEncryptionKeys {
+		aead, err := cookieCipher(key)
+		if err != nil {
+			return "", err
+		}
+
+		if len(sealed) < aead.NonceSize() {
+			return "", ErrInvalidCookieValue
+		}
+
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		if value, err := aead.Open(nil, nonce, ciphertext, []byte(name)); err == nil {
+			return string(value), nil
+		}
+	}
+
+	return "", ErrInvalidCookieValue
+}
+
+// Return the HMAC-SHA256 signature of the cookie name and encoded value
+func signCookie(key []byte, name, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "=" + value))
+
+	return mac.Sum(nil)
+}
+
+// Return the AES-GCM cipher of the key, which must be 16, 24, or 32 bytes long
+func cookieCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
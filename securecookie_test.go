@@ -0,0 +1,150 @@
+package zttp
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Helper to send a cookie from a response back in a request of the same app
+func roundTripCookie(t *testing.T, app *App, set func(*Res) error) *Req {
+	res := &Res{Headers: make(Header), Ctx: &Ctx{Req: &Req{app: app}}}
+	if err := set(res); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	header := res.Headers.Get("Set-Cookie")
+	pair, _, _ := strings.Cut(header, ";")
+
+	return &Req{app: app, Cookies: parseCookieHeader(pair)}
+}
+
+func TestSignedCookie(t *testing.T) {
+	oldKey := []byte("old-signing-key-0123456789abcdef")
+	newKey := []byte("new-signing-key-0123456789abcdef")
+
+	app := NewApp()
+	app.CookieSigningKeys = [][]byte{oldKey}
+
+	req := roundTripCookie(t, app, func(res *Res) error {
+		return res.SetSignedCookie(Cookie{Name: "uid", Value: "42; admin=true", Path: "/"})
+	})
+
+	if value, err := req.SignedCookie("uid"); err != nil || value != "42; admin=true" {
+		t.Errorf("Expected the signed value, got %q, %v", value, err)
+	}
+
+	// Cookies signed with the old key are still accepted after rotation
+	app.CookieSigningKeys = [][]byte{newKey, oldKey}
+	if value, err := req.SignedCookie("uid"); err != nil || value != "42; admin=true" {
+		t.Errorf("Expected the old key to verify, got %q, %v", value, err)
+	}
+
+	// And rejected once the old key is dropped
+	app.CookieSigningKeys = [][]byte{newKey}
+	if _, err := req.SignedCookie("uid"); !errors.Is(err, ErrInvalidCookieValue) {
+		t.Errorf("Expected ErrInvalidCookieValue, got %v", err)
+	}
+
+	app.CookieSigningKeys = [][]byte{oldKey}
+
+	tests := []struct {
+		name     string
+		cookies  map[string]string
+		expected error
+	}{
+		{"Missing cookie", map[string]string{}, ErrCookieNotFound},
+		{"Unsigned value", map[string]string{"uid": "42"}, ErrInvalidCookieValue},
+		{"Tampered value", map[string]string{"uid": "NDM" + req.Cookies["uid"][3:]}, ErrInvalidCookieValue},
+		{"Value moved to another name", map[string]string{"role": req.Cookies["uid"]}, ErrInvalidCookieValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := "uid"
+			if _, ok := tt.cookies["role"]; ok {
+				name = "role"
+			}
+
+			forged := &Req{app: app, Cookies: tt.cookies}
+			if _, err := forged.SignedCookie(name); !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestEncryptedCookie(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 16)
+
+	app := NewApp()
+	app.CookieEncryptionKeys = [][]byte{oldKey}
+
+	req := roundTripCookie(t, app, func(res *Res) error {
+		return res.SetEncryptedCookie(Cookie{Name: "hint", Value: "beta-user", HttpOnly: true})
+	})
+
+	if strings.Contains(req.Cookies["hint"], "beta") {
+		t.Errorf("Expected an encrypted value, got %q", req.Cookies["hint"])
+	}
+
+	if value, err := req.EncryptedCookie("hint"); err != nil || value != "beta-user" {
+		t.Errorf("Expected the decrypted value, got %q, %v", value, err)
+	}
+
+	app.CookieEncryptionKeys = [][]byte{newKey, oldKey}
+	if value, err := req.EncryptedCookie("hint"); err != nil || value != "beta-user" {
+		t.Errorf("Expected the old key to decrypt, got %q, %v", value, err)
+	}
+
+	app.CookieEncryptionKeys = [][]byte{newKey}
+	if _, err := req.EncryptedCookie("hint"); !errors.Is(err, ErrInvalidCookieValue) {
+		t.Errorf("Expected ErrInvalidCookieValue, got %v", err)
+	}
+
+	app.CookieEncryptionKeys = [][]byte{oldKey}
+	moved := &Req{app: app, Cookies: map[string]string{"other": req.Cookies["hint"]}}
+	if _, err := moved.EncryptedCookie("other"); !errors.Is(err, ErrInvalidCookieValue) {
+		t.Errorf("Expected a value moved to another name to be rejected, got %v", err)
+	}
+
+	short := &Req{app: app, Cookies: map[string]string{"hint": "AAAA"}}
+	if _, err := short.EncryptedCookie("hint"); !errors.Is(err, ErrInvalidCookieValue) {
+		t.Errorf("Expected a short value to be rejected, got %v", err)
+	}
+}
+
+func TestCookieKeysRequired(t *testing.T) {
+	app := NewApp()
+	res := &Res{Headers: make(Header), Ctx: &Ctx{Req: &Req{app: app}}}
+	req := &Req{app: app, Cookies: map[string]string{"uid": "42"}}
+
+	if err := res.SetSignedCookie(Cookie{Name: "uid", Value: "42"}); !errors.Is(err, ErrNoCookieKeys) {
+		t.Errorf("Expected ErrNoCookieKeys, got %v", err)
+	}
+	if err := res.SetEncryptedCookie(Cookie{Name: "uid", Value: "42"}); !errors.Is(err, ErrNoCookieKeys) {
+		t.Errorf("Expected ErrNoCookieKeys, got %v", err)
+	}
+	if _, err := req.SignedCookie("uid"); !errors.Is(err, ErrNoCookieKeys) {
+		t.Errorf("Expected ErrNoCookieKeys, got %v", err)
+	}
+	if _, err := req.EncryptedCookie("uid"); !errors.Is(err, ErrNoCookieKeys) {
+		t.Errorf("Expected ErrNoCookieKeys, got %v", err)
+	}
+
+	app.CookieSigningKeys = [][]byte{[]byte("key")}
+	if err := res.SetSignedCookie(Cookie{Name: "bad name", Value: "42"}); !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("Expected ErrInvalidCookie, got %v", err)
+	}
+
+	app.CookieEncryptionKeys = [][]byte{[]byte("short")}
+	if err := res.SetEncryptedCookie(Cookie{Name: "uid", Value: "42"}); err == nil {
+		t.Error("Expected an error for an invalid AES key size")
+	}
+
+	if len(res.Headers["Set-Cookie"]) != 0 {
+		t.Errorf("Expected no cookies to be set, got %q", res.Headers["Set-Cookie"])
+	}
+}
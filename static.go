@@ -0,0 +1,459 @@
+package zttp
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How dotfiles like `.env` or `.git/config` are treated by static file serving
+type DotfilesPolicy int
+
+const (
+	// Respond as if dotfiles don't exist with 404, this is the default
+	DotfilesIgnore DotfilesPolicy = iota
+	// Respond to dotfiles with 403
+	DotfilesDeny
+	// Serve dotfiles like any other file
+	DotfilesAllow
+)
+
+// Options of serving static files
+type StaticOptions struct {
+	// Index files served for a directory, tried in order, `index.html` if empty
+	Index []string
+	// List the directory content when it has no index file
+	Browse bool
+	// How dotfiles are treated
+	Dotfiles DotfilesPolicy
+	// Seconds clients may cache the files for through `Cache-Control`, zero omits the header
+	MaxAge int
+	// Generate an `ETag` for each file from its size and modification time,
+	// or from a hash of its content if it has no modification time, like embedded files
+	ETag bool
+	// `Last-Modified` of files without a modification time, the process start time if zero
+	ModTime time.Time
+	// Serve the `.br` or `.gz` sibling of a file, like `app.js.br` for `app.js`,
+	// to the clients accepting its encoding
+	Precompressed bool
+	// Serve the root index file for missing paths without an extension,
+	// so single page apps can route them on the client side
+	SPA bool
+	// Request path prefixes that keep their 404s in SPA mode, like `/api`
+	SPAExclude []string
+}
+
+// The encodings of precompressed siblings of static files and their extensions, in order of preference
+var precompressedEncodings = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Serves static files from a file system, caching the content hashes of files without a modification time
+type staticServer struct {
+	fsys   fs.FS
+	opt    StaticOptions
+	hashes sync.Map
+}
+
+// The time the process started, used as the modification time of files that have none
+var startTime = time.Now()
+
+// Serve the directory tree under root for all the requests under the passed prefix
+// Example: app.Static("/assets", "./public") serves `./public/css/app.css` at `/assets/css/app.css`
+func (app *App) Static(prefix, root string, opts ...StaticOptions) *Route {
+	return app.StaticFS(prefix, os.DirFS(root), opts...)
+}
+
+// Serve the file system for all the requests under the passed prefix
+// Example: app.StaticFS("/assets", embeddedFiles) serves files embedded with `//go:embed`
+func (app *App) StaticFS(prefix string, fsys fs.FS, opts ...StaticOptions) *Route {
+	return app.Get(path.Join("/", prefix, "*"), staticHandler(fsys, opts))
+}
+
+// Serve the directory tree under root for all the requests under the passed prefix of the router
+func (router *Router) Static(prefix, root string, opts ...StaticOptions) *Route {
+	return router.StaticFS(prefix, os.DirFS(root), opts...)
+}
+
+// Serve the file system for all the requests under the passed prefix of the router
+func (router *Router) StaticFS(prefix string, fsys fs.FS, opts ...StaticOptions) *Route {
+	return router.Get(path.Join("/", prefix, "*"), staticHandler(fsys, opts))
+}
+
+// Create the handler serving the catch-all param of the request from the file system
+func staticHandler(fsys fs.FS, opts []StaticOptions) Handler {
+	server := newStaticServer(fsys, opts)
+
+	return func(req *Req, res *Res) {
+		server.serve(res, req.Param("*"))
+	}
+}
+
+func newStaticServer(fsys fs.FS, opts []StaticOptions) *staticServer {
+	server := &staticServer{fsys: fsys}
+	if len(opts) > 0 {
+		server.opt = opts[0]
+	}
+
+	return server
+}
+
+// Serve the file at the passed URL path of the file system
+func (server *staticServer) serve(res *Res, urlPath string) {
+	opt := server.opt
+
+	name, ok := resolveStaticPath(urlPath)
+	if !ok {
+		res.sendError(404, "")
+		return
+	}
+
+	// Apply the dotfiles policy to every part of the path, not only the file name
+	if opt.Dotfiles != DotfilesAllow && hasDotfile(name) {
+		if opt.Dotfiles == DotfilesDeny {
+			res.sendError(403, "")
+		} else {
+			res.sendError(404, "")
+		}
+		return
+	}
+
+	// Open the file once and take everything we need from the opened file
+	file, err := server.fsys.Open(name)
+	if err != nil {
+		if !server.isSPARoute(res, name) {
+			res.sendError(404, "")
+			return
+		}
+
+		// Let the single page app route the path on the client side
+		indexName, index, _ := openIndex(server.fsys, ".", opt.Index)
+		if index == nil {
+			res.sendError(404, "")
+			return
+		}
+
+		name = indexName
+		file = index
+	}
+
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		res.sendError(500, "")
+		return
+	}
+
+	// If it's a directory, fallback to its index file or its listing
+	if fileInfo.IsDir() {
+		indexName, index, indexInfo := openIndex(server.fsys, name, opt.Index)
+		if index == nil {
+			if opt.Browse {
+				res.listDirectory(server.fsys, name, opt)
+				return
+			}
+
+			res.sendError(403, "")
+			return
+		}
+
+		defer index.Close()
+
+		name = indexName
+		file = index
+		fileInfo = indexInfo
+	}
+
+	// Set content type based on file extension, even if a precompressed sibling is served
+	res.ContentType = mime.TypeByExtension(path.Ext(name))
+	if res.ContentType == "" {
+		res.ContentType = "application/octet-stream"
+	}
+
+	if opt.Precompressed {
+		encodedName, encoded, encodedInfo, encoding := server.openPrecompressed(res, name)
+		if encoded != nil {
+			defer encoded.Close()
+
+			name = encodedName
+			file = encoded
+			fileInfo = encodedInfo
+			res.Header("Content-Encoding", encoding)
+		}
+	}
+
+	// Set the caching headers, files without a modification time fall back to a fixed one
+	modTime := fileInfo.ModTime()
+	hasModTime := !modTime.IsZero()
+	if !hasModTime {
+		modTime = opt.ModTime
+		if modTime.IsZero() {
+			modTime = startTime
+		}
+	}
+	res.Header("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+
+	etag := ""
+	if opt.ETag {
+		if hasModTime {
+			etag = fmt.Sprintf(`W/"%x-%x"`, fileInfo.Size(), modTime.UnixNano())
+		} else if etag, err = server.contentHash(name, fileInfo.Size()); err != nil {
+			res.sendError(500, "")
+			return
+		}
+		res.Header("ETag", etag)
+	}
+
+	if opt.MaxAge > 0 {
+		res.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", opt.MaxAge))
+	}
+
+	// Stream the file content, or only the requested ranges of it
+	res.serveContent(file, fileInfo.Size(), modTime, etag)
+}
+
+// Check if the missing file is a client-side route of the single page app
+// Paths with an extension are missing files, and the excluded prefixes keep their 404s
+func (server *staticServer) isSPARoute(res *Res, name string) bool {
+	if !server.opt.SPA || path.Ext(name) != "" {
+		return false
+	}
+
+	requestPath := "/" + name
+	if res.Ctx != nil && res.Ctx.Req != nil {
+		requestPath = res.Ctx.Req.Path
+	}
+
+	for _, prefix := range server.opt.SPAExclude {
+		prefix = "/" + strings.Trim(prefix, "/")
+		if requestPath == prefix || strings.HasPrefix(requestPath, prefix+"/") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Open the precompressed sibling of the file in the best encoding the client accepts
+// Return a nil file if there's no such sibling, then the file itself should be served
+func (server *staticServer) openPrecompressed(res *Res, name string) (string, fs.File, fs.FileInfo, string) {
+	var offered []string
+	for _, enc := range precompressedEncodings {
+		if info, err := fs.Stat(server.fsys, name+enc.ext); err == nil && !info.IsDir() {
+			offered = append(offered, enc.encoding)
+		}
+	}
+
+	if len(offered) == 0 {
+		return "", nil, nil, ""
+	}
+
+	// Once a sibling exists, the response depends on the encodings the client accepts
+	res.Vary("Accept-Encoding")
+
+	// Clients that don't send `Accept-Encoding` get the file as is
+	if res.Ctx == nil || res.Ctx.Req == nil || res.Ctx.Req.Header("Accept-Encoding") == "" {
+		return "", nil, nil, ""
+	}
+
+	encoding := res.Ctx.Req.AcceptsEncodings(append(offered, "identity")...)
+
+	for _, enc := range precompressedEncodings {
+		if enc.encoding != encoding {
+			continue
+		}
+
+		file, err := server.fsys.Open(name + enc.ext)
+		if err != nil {
+			break
+		}
+
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			break
+		}
+
+		return name + enc.ext, file, info, encoding
+	}
+
+	return "", nil, nil, ""
+}
+
+// Return a strong ETag hashed from the file content
+// Files without a modification time are expected to never change, so the hash is cached
+func (server *staticServer) contentHash(name string, size int64) (string, error) {
+	key := fmt.Sprintf("%s:%d", name, size)
+	if etag, ok := server.hashes.Load(key); ok {
+		return etag.(string), nil
+	}
+
+	// Hash from a separate handle, so the served file is still read from its start
+	file, err := server.fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	etag := hashETag(hash.Sum(nil), false)
+	server.hashes.Store(key, etag)
+
+	return etag, nil
+}
+
+// Resolve the URL path to a file system name that can never leave the file system root
+// Return false if the path can't be resolved safely
+func resolveStaticPath(urlPath string) (string, bool) {
+	// Request paths reach us percent-encoded
+	decoded, err := url.PathUnescape(urlPath)
+	if err != nil {
+		return "", false
+	}
+
+	// Backslashes are separators on some systems, and NUL bytes truncate paths on others
+	if strings.ContainsAny(decoded, "\\\x00") {
+		return "", false
+	}
+
+	// Cleaning a rooted path drops every `..` that would climb above the root
+	name := strings.TrimPrefix(path.Clean("/"+decoded), "/")
+	if name == "" {
+		name = "."
+	}
+
+	return name, fs.ValidPath(name)
+}
+
+// Check if any part of the slash separated path is a dotfile
+func hasDotfile(name string) bool {
+	if name == "." {
+		return false
+	}
+
+	for part := range strings.SplitSeq(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Open the first index file that exists in the directory and return its name
+func openIndex(fsys fs.FS, dir string, indexes []string) (string, fs.File, fs.FileInfo) {
+	if len(indexes) == 0 {
+		indexes = []string{"index.html"}
+	}
+
+	for _, index := range indexes {
+		name := path.Join(dir, index)
+		file, err := fsys.Open(name)
+		if err != nil {
+			continue
+		}
+
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			file.Close()
+			continue
+		}
+
+		return name, file, info
+	}
+
+	return "", nil, nil
+}
+
+// Send an HTML page listing the entries of the directory
+func (res *Res) listDirectory(fsys fs.FS, dir string, opt StaticOptions) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		res.sendError(500, "")
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	// Links are absolute so they work whether the directory URL ends with `/` or not
+	base := "/"
+	if res.Ctx != nil && res.Ctx.Req != nil {
+		base = strings.TrimSuffix(res.Ctx.Req.Path, "/") + "/"
+	}
+
+	relPath := strings.TrimPrefix(dir, ".")
+
+	var sb strings.Builder
+	title := html.EscapeString("/" + relPath)
+	fmt.Fprintf(&sb, "<!DOCTYPE html>\n<html>\n<head><title>Index of %s</title></head>\n<body>\n", title)
+	fmt.Fprintf(&sb, "<h1>Index of %s</h1>\n<ul>\n", title)
+
+	if relPath != "" {
+		fmt.Fprintf(&sb, "<li><a href=\"%s\">../</a></li>\n", html.EscapeString(path.Dir(strings.TrimSuffix(base, "/"))))
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if opt.Dotfiles != DotfilesAllow && strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		if entry.IsDir() {
+			name += "/"
+		}
+
+		href := base + (&url.URL{Path: name}).EscapedPath()
+		fmt.Fprintf(&sb, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(href), html.EscapeString(name))
+	}
+
+	sb.WriteString("</ul>\n</body>\n</html>\n")
+
+	res.ContentType = "text/html; charset=utf-8"
+	res.Send(sb.String())
+}
+
+// Check the conditional request headers against the passed validators
+// `If-None-Match` takes precedence over `If-Modified-Since` as RFC 9110 says
+func (res *Res) notModified(etag string, modTime time.Time) bool {
+	if res.Ctx == nil || res.Ctx.Req == nil {
+		return false
+	}
+
+	req := res.Ctx.Req
+
+	if noneMatch := req.Header("If-None-Match"); noneMatch != "" {
+		return etag != "" && (noneMatch == "*" || !isEtagStale(etag, []byte(noneMatch)))
+	}
+
+	if modifiedSince := req.Header("If-Modified-Since"); modifiedSince != "" && !modTime.IsZero() {
+		t, err := http.ParseTime(modifiedSince)
+
+		// The header has a one second precision
+		return err == nil && !modTime.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
@@ -0,0 +1,444 @@
+package zttp
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// Helper to create a directory tree for static file serving tests
+func createStaticTree(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+
+	for name, content := range files {
+		fullPath := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(fullPath), DefaultDirPerm)
+		if err := os.WriteFile(fullPath, []byte(content), DefaultFilePerm); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	return root
+}
+
+func TestAppStatic(t *testing.T) {
+	root := createStaticTree(t, map[string]string{
+		"index.html":         "<h1>root index</h1>",
+		"css/app.css":        "body {}",
+		"docs/readme.txt":    "read me",
+		"docs/guide/a b.txt": "spaces",
+		"home/default.htm":   "<h1>default</h1>",
+		".env":               "SECRET=1",
+		".git/config":        "[core]",
+	})
+
+	outside := filepath.Join(filepath.Dir(root), "outside.txt")
+	os.WriteFile(outside, []byte("outside"), DefaultFilePerm)
+	defer os.Remove(outside)
+
+	tests := []struct {
+		name             string
+		opts             StaticOptions
+		path             string
+		shouldContain    []string
+		shouldNotContain []string
+	}{
+		{
+			name:          "Serve nested file",
+			path:          "/assets/css/app.css",
+			shouldContain: []string{"HTTP/1.1 200 OK", "Content-Type: text/css", "body {}"},
+		},
+		{
+			name:          "Serve root index",
+			path:          "/assets",
+			shouldContain: []string{"HTTP/1.1 200 OK", "<h1>root index</h1>"},
+		},
+		{
+			name:          "Serve percent-encoded path",
+			path:          "/assets/docs/guide/a%20b.txt",
+			shouldContain: []string{"HTTP/1.1 200 OK", "spaces"},
+		},
+		{
+			name:          "Custom index files",
+			opts:          StaticOptions{Index: []string{"index.html", "default.htm"}},
+			path:          "/assets/home",
+			shouldContain: []string{"HTTP/1.1 200 OK", "<h1>default</h1>"},
+		},
+		{
+			name:          "Directory without index",
+			path:          "/assets/docs",
+			shouldContain: []string{"HTTP/1.1 403 Forbidden"},
+		},
+		{
+			name:             "Directory listing",
+			opts:             StaticOptions{Browse: true},
+			path:             "/assets/docs",
+			shouldContain:    []string{"HTTP/1.1 200 OK", `href="/assets/docs/readme.txt"`, `href="/assets/docs/guide/"`},
+			shouldNotContain: []string{".env"},
+		},
+		{
+			name:          "Missing file",
+			path:          "/assets/missing.css",
+			shouldContain: []string{"HTTP/1.1 404 Not Found"},
+		},
+		{
+			name:             "Traversal",
+			path:             "/assets/../outside.txt",
+			shouldNotContain: []string{"outside"},
+		},
+		{
+			name:             "Encoded traversal",
+			path:             "/assets/%2e%2e/outside.txt",
+			shouldNotContain: []string{"outside"},
+		},
+		{
+			name:          "Dotfiles ignored by default",
+			path:          "/assets/.env",
+			shouldContain: []string{"HTTP/1.1 404 Not Found"},
+		},
+		{
+			name:          "Dotfile directories ignored by default",
+			path:          "/assets/.git/config",
+			shouldContain: []string{"HTTP/1.1 404 Not Found"},
+		},
+		{
+			name:          "Dotfiles denied",
+			opts:          StaticOptions{Dotfiles: DotfilesDeny},
+			path:          "/assets/.env",
+			shouldContain: []string{"HTTP/1.1 403 Forbidden"},
+		},
+		{
+			name:          "Dotfiles allowed",
+			opts:          StaticOptions{Dotfiles: DotfilesAllow},
+			path:          "/assets/.env",
+			shouldContain: []string{"HTTP/1.1 200 OK", "SECRET=1"},
+		},
+		{
+			name:          "Cache-Control max-age",
+			opts:          StaticOptions{MaxAge: 3600},
+			path:          "/assets/css/app.css",
+			shouldContain: []string{"Cache-Control: public, max-age=3600"},
+		},
+		{
+			name:          "ETag generation",
+			opts:          StaticOptions{ETag: true},
+			path:          "/assets/css/app.css",
+			shouldContain: []string{`ETag: W/"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewApp()
+			app.Static("/assets", root, tt.opts)
+
+			response := mockRequest(app, "GET", tt.path, "", nil)
+
+			for _, expected := range tt.shouldContain {
+				if !strings.Contains(response, expected) {
+					t.Errorf("Expected response to contain %q, got: %s", expected, response)
+				}
+			}
+
+			for _, unexpected := range tt.shouldNotContain {
+				if strings.Contains(response, unexpected) {
+					t.Errorf("Expected response NOT to contain %q, got: %s", unexpected, response)
+				}
+			}
+		})
+	}
+}
+
+func TestStaticConditionalRequests(t *testing.T) {
+	root := createStaticTree(t, map[string]string{"app.js": "console.log(1)"})
+
+	modTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(filepath.Join(root, "app.js"), modTime, modTime)
+
+	app := NewApp()
+	app.Static("/", root, StaticOptions{ETag: true})
+
+	// Take the generated ETag from a first unconditional request
+	response := mockRequest(app, "GET", "/app.js", "", nil)
+	etag := ""
+	for line := range strings.SplitSeq(response, "\r\n") {
+		if value, ok := strings.CutPrefix(line, "ETag: "); ok {
+			etag = value
+		}
+	}
+
+	if etag == "" {
+		t.Fatalf("Expected an ETag, got: %s", response)
+	}
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected string
+	}{
+		{
+			name:     "Matching If-None-Match",
+			headers:  map[string]string{"If-None-Match": etag},
+			expected: "HTTP/1.1 304 Not Modified",
+		},
+		{
+			name:     "Stale If-None-Match",
+			headers:  map[string]string{"If-None-Match": `W/"other"`},
+			expected: "HTTP/1.1 200 OK",
+		},
+		{
+			name:     "If-Modified-Since after modification",
+			headers:  map[string]string{"If-Modified-Since": modTime.Add(time.Hour).Format(http.TimeFormat)},
+			expected: "HTTP/1.1 304 Not Modified",
+		},
+		{
+			name:     "If-Modified-Since before modification",
+			headers:  map[string]string{"If-Modified-Since": modTime.Add(-time.Hour).Format(http.TimeFormat)},
+			expected: "HTTP/1.1 200 OK",
+		},
+		{
+			name: "If-None-Match takes precedence",
+			headers: map[string]string{
+				"If-None-Match":     `W/"other"`,
+				"If-Modified-Since": modTime.Add(time.Hour).Format(http.TimeFormat),
+			},
+			expected: "HTTP/1.1 200 OK",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := mockRequest(app, "GET", "/app.js", "", tt.headers)
+			if !strings.HasPrefix(response, tt.expected) {
+				t.Errorf("Expected response to start with %q, got: %s", tt.expected, response)
+			}
+		})
+	}
+}
+
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":  {Data: []byte("<h1>embedded index</h1>")},
+		"js/app.js":   {Data: []byte("console.log(1)")},
+		"js/other.js": {Data: []byte("console.log(2)")},
+		"dated.txt":   {Data: []byte("dated"), ModTime: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	fallback := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	app := NewApp()
+	app.StaticFS("/assets", fsys, StaticOptions{ETag: true, ModTime: fallback})
+
+	etagOf := func(response string) string {
+		for line := range strings.SplitSeq(response, "\r\n") {
+			if value, ok := strings.CutPrefix(line, "ETag: "); ok {
+				return value
+			}
+		}
+		return ""
+	}
+
+	response := mockRequest(app, "GET", "/assets/js/app.js", "", nil)
+	if !strings.HasPrefix(response, "HTTP/1.1 200 OK") || !strings.HasSuffix(response, "console.log(1)") {
+		t.Fatalf("Expected the embedded file, got: %s", response)
+	}
+
+	if !strings.Contains(response, "Last-Modified: "+fallback.Format(http.TimeFormat)) {
+		t.Errorf("Expected the fallback modification time, got: %s", response)
+	}
+
+	// Files without a modification time get strong ETags hashed from their content
+	etag := etagOf(response)
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		t.Fatalf("Expected a strong content hash ETag, got: %q", etag)
+	}
+
+	if again := etagOf(mockRequest(app, "GET", "/assets/js/app.js", "", nil)); again != etag {
+		t.Errorf("Expected a stable ETag %q, got %q", etag, again)
+	}
+
+	if other := etagOf(mockRequest(app, "GET", "/assets/js/other.js", "", nil)); other == etag {
+		t.Errorf("Expected different content to have a different ETag, got %q for both", etag)
+	}
+
+	response = mockRequest(app, "GET", "/assets/js/app.js", "", map[string]string{"If-None-Match": etag})
+	if !strings.HasPrefix(response, "HTTP/1.1 304 Not Modified") {
+		t.Errorf("Expected 304 for a matching content hash, got: %s", response)
+	}
+
+	// Files with a modification time keep using it
+	response = mockRequest(app, "GET", "/assets/dated.txt", "", nil)
+	if !strings.Contains(response, "Last-Modified: Wed, 01 Jan 2025 00:00:00 GMT") || !strings.Contains(response, `ETag: W/"`) {
+		t.Errorf("Expected the file's own modification time, got: %s", response)
+	}
+
+	response = mockRequest(app, "GET", "/assets", "", nil)
+	if !strings.Contains(response, "<h1>embedded index</h1>") {
+		t.Errorf("Expected the embedded index, got: %s", response)
+	}
+
+	response = mockRequest(app, "GET", "/assets/js/missing.js", "", nil)
+	if !strings.HasPrefix(response, "HTTP/1.1 404 Not Found") {
+		t.Errorf("Expected 404 for a missing embedded file, got: %s", response)
+	}
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	root := createStaticTree(t, map[string]string{
+		"app.js":       "raw js",
+		"app.js.br":    "brotli js",
+		"app.js.gz":    "gzip js",
+		"style.css":    "raw css",
+		"style.css.gz": "gzip css",
+		"plain.txt":    "raw text",
+	})
+
+	app := NewApp()
+	app.Static("/", root, StaticOptions{Precompressed: true, ETag: true})
+
+	tests := []struct {
+		name             string
+		path             string
+		acceptEncoding   string
+		shouldContain    []string
+		shouldNotContain []string
+	}{
+		{
+			name:           "Brotli accepted",
+			path:           "/app.js",
+			acceptEncoding: "br, gzip",
+			shouldContain:  []string{"Content-Encoding: br", "Content-Type: text/javascript", "Vary: Accept-Encoding", "brotli js"},
+		},
+		{
+			name:           "Client quality preferred",
+			path:           "/app.js",
+			acceptEncoding: "br;q=0.5, gzip",
+			shouldContain:  []string{"Content-Encoding: gzip", "gzip js"},
+		},
+		{
+			name:           "Only the existing sibling",
+			path:           "/style.css",
+			acceptEncoding: "br, gzip",
+			shouldContain:  []string{"Content-Encoding: gzip", "Content-Type: text/css", "gzip css"},
+		},
+		{
+			name:             "Unsupported encoding falls back to the raw file",
+			path:             "/style.css",
+			acceptEncoding:   "br",
+			shouldContain:    []string{"Vary: Accept-Encoding", "raw css"},
+			shouldNotContain: []string{"Content-Encoding"},
+		},
+		{
+			name:             "No Accept-Encoding falls back to the raw file",
+			path:             "/app.js",
+			shouldContain:    []string{"Vary: Accept-Encoding", "raw js"},
+			shouldNotContain: []string{"Content-Encoding"},
+		},
+		{
+			name:             "No siblings",
+			path:             "/plain.txt",
+			acceptEncoding:   "gzip, br",
+			shouldContain:    []string{"raw text"},
+			shouldNotContain: []string{"Content-Encoding", "Vary"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers map[string]string
+			if tt.acceptEncoding != "" {
+				headers = map[string]string{"Accept-Encoding": tt.acceptEncoding}
+			}
+
+			response := mockRequest(app, "GET", tt.path, "", headers)
+
+			for _, expected := range tt.shouldContain {
+				if !strings.Contains(response, expected) {
+					t.Errorf("Expected response to contain %q, got: %s", expected, response)
+				}
+			}
+
+			for _, unexpected := range tt.shouldNotContain {
+				if strings.Contains(response, unexpected) {
+					t.Errorf("Expected response NOT to contain %q, got: %s", unexpected, response)
+				}
+			}
+		})
+	}
+}
+
+func TestStaticSPA(t *testing.T) {
+	root := createStaticTree(t, map[string]string{
+		"index.html":     "<div id=\"root\"></div>",
+		"static/main.js": "render()",
+	})
+
+	app := NewApp()
+	app.Static("/", root, StaticOptions{SPA: true, SPAExclude: []string{"/api", "internal/"}})
+	app.Get("/api/status", func(req *Req, res *Res) {
+		res.Send("ok")
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{"Existing file", "/static/main.js", []string{"HTTP/1.1 200 OK", "render()"}},
+		{"Root", "/", []string{"HTTP/1.1 200 OK", `<div id="root"></div>`}},
+		{"Client-side route", "/users/42/settings", []string{"HTTP/1.1 200 OK", "Content-Type: text/html", `<div id="root"></div>`}},
+		{"Missing file with an extension", "/static/missing.js", []string{"HTTP/1.1 404 Not Found"}},
+		{"Route registered after the static one", "/api/status", []string{"HTTP/1.1 200 OK", "ok"}},
+		{"Excluded prefix", "/api/users", []string{"HTTP/1.1 404 Not Found"}},
+		{"Excluded prefix itself", "/api", []string{"HTTP/1.1 404 Not Found"}},
+		{"Excluded prefix without leading slash", "/internal/health", []string{"HTTP/1.1 404 Not Found"}},
+		{"Path sharing a prefix with an excluded one", "/apiary", []string{"HTTP/1.1 200 OK", `<div id="root"></div>`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := mockRequest(app, "GET", tt.path, "", nil)
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(response, expected) {
+					t.Errorf("Expected response to contain %q, got: %s", expected, response)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveStaticPath(t *testing.T) {
+	tests := []struct {
+		urlPath  string
+		name     string
+		resolved bool
+	}{
+		{"css/app.css", "css/app.css", true},
+		{"", ".", true},
+		{"../../etc/passwd", "etc/passwd", true},
+		{"a/../../b", "b", true},
+		{"%2e%2e/secret", "secret", true},
+		{"a%2fb", "a/b", true},
+		{"a\\..\\b", "", false},
+		{"a%00b", "", false},
+		{"%zz", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.urlPath, func(t *testing.T) {
+			name, ok := resolveStaticPath(tt.urlPath)
+			if ok != tt.resolved {
+				t.Fatalf("Expected resolved %v, got %v", tt.resolved, ok)
+			}
+
+			if ok && name != tt.name {
+				t.Errorf("Expected name %q, got %q", tt.name, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package zttp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// What to do when the saved file name already exists in the destination
+type CollisionPolicy int
+
+const (
+	// Replace the existing file, this is the default
+	CollisionOverwrite CollisionPolicy = iota
+	// Keep the existing file and save the new one with a numeric suffix, like `photo_1.png`
+	CollisionRename
+	// Keep the existing file and fail with ErrFileExists
+	CollisionError
+)
+
+// Longest file name most file systems accept, in bytes
+const maxFilenameLength = 255
+
+var (
+	// Returned when saving with CollisionError and the file already exists
+	ErrFileExists = errors.New("file already exists")
+	// Returned when the sniffed content type isn't in the allowed types
+	ErrFileTypeNotAllowed = errors.New("file type not allowed")
+)
+
+// Options of saving a multipart form file to disk
+type SaveOptions struct {
+	// What to do when a file with the same name already exists
+	OnCollision CollisionPolicy
+	// MIME types sniffed from the content that are allowed to be saved, like `image/png` or `image/*`
+	// If empty, all types are allowed
+	AllowedTypes []string
+	// Permissions of the saved file, DefaultFilePerm if zero
+	Perm os.FileMode
+}
+
+// Windows device names that can't be used as file names, whatever the extension is
+var reservedFilenames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Lookalike characters that some file systems and tools fold into path separators or dots
+var filenameFolds = strings.NewReplacer(
+	"\\", "/",
+	"／", "/", // FULLWIDTH SOLIDUS
+	"∕", "/", // DIVISION SLASH
+	"⁄", "/", // FRACTION SLASH
+	"⧸", "/", // BIG SOLIDUS
+	"＼", "/", // FULLWIDTH REVERSE SOLIDUS
+	"﹨", "/", // SMALL REVERSE SOLIDUS
+	"⧵", "/", // REVERSE SOLIDUS OPERATOR
+	"．", ".", // FULLWIDTH FULL STOP
+	"․", ".", // ONE DOT LEADER
+	"﹒", ".", // SMALL FULL STOP
+)
+
+// Return a file name that is safe to join with a destination directory
+// Only the last path element is kept, lookalike separators and dots are folded,
+// control, format and shell-hostile characters are dropped or replaced, leading dots
+// and trailing dots and spaces are trimmed, and reserved device names are prefixed
+// The name is normalized to NFC first, so names that look the same are saved under the same bytes,
+// whether the client sent them composed like "é" or decomposed like "e" and a combining accent
+// An empty string is returned if nothing usable is left
+func SanitizeFilename(name string) string {
+	name = strings.ToValidUTF8(name, "")
+	name = norm.NFC.String(name)
+	name = filenameFolds.Replace(name)
+
+	// Keep only the last path element, this drops any `../` traversal
+	name = name[strings.LastIndex(name, "/")+1:]
+
+	var sb strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			// Drop invisible characters, like bidi overrides that spoof extensions
+			continue
+		case strings.ContainsRune(`<>:"|?*`, r):
+			sb.WriteRune('_')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	name = strings.TrimLeft(sb.String(), ". ")
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return ""
+	}
+
+	// Device names are reserved even with an extension, like `CON.txt`
+	stem, _, _ := strings.Cut(name, ".")
+	if reservedFilenames[strings.ToUpper(strings.TrimSpace(stem))] {
+		name = "_" + name
+	}
+
+	return truncateFilename(name, maxFilenameLength)
+}
+
+// Truncate the file name to the passed number of bytes, keeping its extension if possible
+func truncateFilename(name string, limit int) string {
+	if len(name) <= limit {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	if len(ext) >= limit {
+		ext = ""
+	}
+
+	stem := name[:len(name)-len(ext)]
+	stem = stem[:limit-len(ext)]
+
+	// Don't cut a multi-byte rune in half
+	for len(stem) > 0 && !utf8.ValidString(stem) {
+		stem = stem[:len(stem)-1]
+	}
+
+	return stem + ext
+}
+
+// Check if the sniffed content type matches one of the allowed types, which may be wildcards like `image/*`
+// The types are matched like the media ranges of an `Accept` header
+func isTypeAllowed(contentType string, allowed []string) bool {
+	for _, r := range parseAcceptRanges(strings.Join(allowed, ",")) {
+		if _, ok := matchMediaType(r, contentType); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Move the fully written temp file to its final name in the destination directory
+// following the passed collision policy, and return the final path
+func commitUpload(tmpPath, destination, filename string, policy CollisionPolicy) (string, error) {
+	fullPath := filepath.Join(destination, filename)
+
+	if policy == CollisionOverwrite {
+		if err := os.Rename(tmpPath, fullPath); err != nil {
+			return "", fmt.Errorf("failed to save file: %w", err)
+		}
+		return fullPath, nil
+	}
+
+	ext := filepath.Ext(filename)
+	stem := strings.TrimSuffix(filename, ext)
+
+	for i := 0; ; i++ {
+		candidate := fullPath
+		if i > 0 {
+			// Shorten the stem rather than losing the suffix to the length limit
+			suffix := fmt.Sprintf("_%d", i)
+			candidate = filepath.Join(destination, truncateFilename(stem, maxFilenameLength-len(suffix)-len(ext))+suffix+ext)
+		}
+
+		// Reserve the name atomically so concurrent saves can't pick the same one
+		placeholder, err := os.OpenFile(candidate, os.O_WRONLY|os.O_CREATE|os.O_EXCL, DefaultFilePerm)
+		if errors.Is(err, os.ErrExist) {
+			if policy == CollisionError {
+				return "", fmt.Errorf("failed to save file: %s: %w", filename, ErrFileExists)
+			}
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to save file: %w", err)
+		}
+		placeholder.Close()
+
+		// Replace the reserved empty file with the content
+		if err := os.Rename(tmpPath, candidate); err != nil {
+			os.Remove(candidate)
+			return "", fmt.Errorf("failed to save file: %w", err)
+		}
+
+		return candidate, nil
+	}
+}
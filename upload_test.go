@@ -0,0 +1,202 @@
+package zttp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Plain name", "photo.png", "photo.png"},
+		{"Unicode name", "صورة.png", "صورة.png"},
+		{"Unix traversal", "../../etc/passwd", "passwd"},
+		{"Windows traversal", `..\..\windows\win.ini`, "win.ini"},
+		{"Absolute path", "/etc/x", "x"},
+		{"Fullwidth solidus traversal", "..／..／etc／x", "x"},
+		{"Fullwidth dots", "．．", ""},
+		{"Only dots", "..", ""},
+		{"Empty name", "", ""},
+		{"Hidden file", ".htaccess", "htaccess"},
+		{"Trailing dots and spaces", "report.pdf. . ", "report.pdf"},
+		{"Control characters", "bad\x00na\nme.txt", "badname.txt"},
+		{"Bidi override", "invoice‮fdp.exe", "invoicefdp.exe"},
+		{"Reserved characters", `a<b>c:d"e|f?g*.txt`, "a_b_c_d_e_f_g_.txt"},
+		{"Reserved device name", "CON", "_CON"},
+		{"Reserved device name with extension", "nul.txt", "_nul.txt"},
+		{"Not a reserved device name", "console.txt", "console.txt"},
+		{"Invalid UTF-8", "na\xffme.txt", "name.txt"},
+		{"Decomposed name is composed", "cafe\u0301.txt", "caf\u00e9.txt"},
+		{"Composed name is kept", "caf\u00e9.txt", "caf\u00e9.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeFilename(tt.input); got != tt.expected {
+				t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSanitizeFilenameLength(t *testing.T) {
+	name := strings.Repeat("é", 200) + ".jpeg"
+	got := SanitizeFilename(name)
+
+	if len(got) > maxFilenameLength {
+		t.Errorf("Expected at most %d bytes, got %d", maxFilenameLength, len(got))
+	}
+
+	if !strings.HasSuffix(got, ".jpeg") {
+		t.Errorf("Expected the extension to be kept, got %q", got)
+	}
+
+	if !strings.HasPrefix(got, "é") || strings.ContainsRune(got, '�') {
+		t.Errorf("Expected runes to be kept whole, got %q", got)
+	}
+}
+
+func TestSaveFileCollisions(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        CollisionPolicy
+		expectedErr   error
+		expectedFile  string
+		existingAfter string
+	}{
+		{
+			name:          "Overwrite",
+			policy:        CollisionOverwrite,
+			expectedFile:  "test.txt",
+			existingAfter: "new content",
+		},
+		{
+			name:          "Rename",
+			policy:        CollisionRename,
+			expectedFile:  "test_1.txt",
+			existingAfter: "old content",
+		},
+		{
+			name:          "Error",
+			policy:        CollisionError,
+			expectedErr:   ErrFileExists,
+			existingAfter: "old content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDir := t.TempDir()
+			existing := filepath.Join(testDir, "test.txt")
+			os.WriteFile(existing, []byte("old content"), DefaultFilePerm)
+
+			req := &Req{}
+			formFile := &FormFile{Filename: "test.txt", Content: []byte("new content")}
+
+			fullPath, err := req.SaveFile(formFile, testDir, SaveOptions{OnCollision: tt.policy})
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("Expected error %v, got %v", tt.expectedErr, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if fullPath != filepath.Join(testDir, tt.expectedFile) {
+					t.Errorf("Expected path %s, got %s", filepath.Join(testDir, tt.expectedFile), fullPath)
+				}
+
+				content, _ := os.ReadFile(fullPath)
+				if string(content) != "new content" {
+					t.Errorf("Expected saved content %q, got %q", "new content", content)
+				}
+			}
+
+			content, _ := os.ReadFile(existing)
+			if string(content) != tt.existingAfter {
+				t.Errorf("Expected existing file content %q, got %q", tt.existingAfter, content)
+			}
+
+			// No temp files should be left behind
+			entries, _ := os.ReadDir(testDir)
+			for _, entry := range entries {
+				if strings.HasPrefix(entry.Name(), ".zttp-upload-") {
+					t.Errorf("Temp file left behind: %s", entry.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestSaveFileTraversal(t *testing.T) {
+	testDir := t.TempDir()
+	destination := filepath.Join(testDir, "uploads")
+
+	req := &Req{}
+	formFile := &FormFile{Filename: "../../escaped.txt", Content: []byte("content")}
+
+	fullPath, err := req.SaveFile(formFile, destination, SaveOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if fullPath != filepath.Join(destination, "escaped.txt") {
+		t.Errorf("Expected file inside the destination, got %s", fullPath)
+	}
+
+	if _, err := os.Stat(filepath.Join(testDir, "escaped.txt")); !os.IsNotExist(err) {
+		t.Errorf("File escaped the destination directory")
+	}
+}
+
+func TestSaveFileAllowedTypes(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 16))
+
+	tests := []struct {
+		name        string
+		content     []byte
+		allowed     []string
+		expectedErr error
+	}{
+		{"Exact type allowed", png, []string{"image/png"}, nil},
+		{"Wildcard type allowed", png, []string{"image/*"}, nil},
+		{"Types are case-insensitive", png, []string{"Image/PNG"}, nil},
+		{"Sniffed params are ignored", []byte("plain text"), []string{"text/plain"}, nil},
+		{"Type not allowed", []byte("<html><body>hi</body></html>"), []string{"image/*"}, ErrFileTypeNotAllowed},
+		{"Spoofed extension", []byte("plain text"), []string{"image/png"}, ErrFileTypeNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDir := t.TempDir()
+
+			req := &Req{}
+			formFile := &FormFile{Filename: "upload.png", Content: tt.content}
+
+			fullPath, err := req.SaveFile(formFile, testDir, SaveOptions{AllowedTypes: tt.allowed})
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("Expected error %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// The sniffed bytes must still be written to disk
+			content, _ := os.ReadFile(fullPath)
+			if string(content) != string(tt.content) {
+				t.Errorf("Saved content mismatch")
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package zttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Returned when a view is rendered while the app has no view engine
+var ErrNoViewEngine = errors.New("no view engine configured")
+
+// A template engine rendering the views of the app, set on `app.Views`
+type ViewEngine interface {
+	// Render the view with the passed name and data into w, wrapped in the layout unless it's empty
+	Render(w io.Writer, name string, data any, layout string) error
+}
+
+// Options of the html/template view engine
+type ViewOptions struct {
+	// The extension of the template files, ".html" by default
+	Extension string
+	// The directory of the partials, "partials" by default
+	// Every template in it can be included by its path, like {{ template "partials/header" . }}
+	Partials string
+	// Functions available to all the templates, on top of the built-in ones
+	Funcs template.FuncMap
+	// Parse the templates again on every render, so changes show up without a restart
+	// Meant for development, the parsed templates are cached otherwise
+	Reload bool
+}
+
+// The default view engine, rendering html/template files
+// A view is wrapped in a layout by parsing it as the "content" template,
+// so the layout includes it with {{ template "content" . }}
+// Blocks of the layout, like {{ block "title" . }}, can be overridden by the view with {{ define "title" }}
+type HTMLEngine struct {
+	fsys fs.FS
+	opt  ViewOptions
+
+	// The parsed templates, keyed by the view and layout names
+	cache sync.Map
+}
+
+// Create an html/template view engine rendering the templates of the passed directory
+// Example: app.Views = zttp.NewHTMLEngine("./views")
+func NewHTMLEngine(dir string, opts ...ViewOptions) *HTMLEngine {
+	return NewHTMLEngineFS(os.DirFS(dir), opts...)
+}
+
+// Create an html/template view engine rendering the templates of the passed file system,
+// like an embed.FS compiled into the binary
+func NewHTMLEngineFS(fsys fs.FS, opts ...ViewOptions) *HTMLEngine {
+	var opt ViewOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	if opt.Extension == "" {
+		opt.Extension = ".html"
+	}
+	if !strings.HasPrefix(opt.Extension, ".") {
+		opt.Extension = "." + opt.Extension
+	}
+
+	if opt.Partials == "" {
+		opt.Partials = "partials"
+	}
+
+	return &HTMLEngine{fsys: fsys, opt: opt}
+}
+
+// Render the view with the passed name, like "users/show", wrapped in the layout unless it's empty
+func (engine *HTMLEngine) Render(w io.Writer, name string, data any, layout string) error {
+	tmpl, err := engine.template(name, layout)
+	if err != nil {
+		return err
+	}
+
+	if layout != "" {
+		return tmpl.ExecuteTemplate(w, layout, data)
+	}
+
+	return tmpl.ExecuteTemplate(w, "content", data)
+}
+
+// Return the parsed templates of the view and layout, from the cache unless reloading is enabled
+func (engine *HTMLEngine) template(name, layout string) (*template.Template, error) {
+	key := name + "\x00" + layout
+
+	if !engine.opt.Reload {
+		if cached, ok := engine.cache.Load(key); ok {
+			return cached.(*template.Template), nil
+		}
+	}
+
+	tmpl, err := engine.parse(name, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	if !engine.opt.Reload {
+		engine.cache.Store(key, tmpl)
+	}
+
+	return tmpl, nil
+}
+
+// Parse the partials, the layout, and the view into a single template set
+// The view is parsed last, so its definitions override the blocks of the layout
+func (engine *HTMLEngine) parse(name, layout string) (*template.Template, error) {
+	tmpl := template.New("").Funcs(engine.opt.Funcs)
+
+	if err := engine.parsePartials(tmpl); err != nil {
+		return nil, err
+	}
+
+	if layout != "" {
+		if err := engine.parseFile(tmpl, layout, layout); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := engine.parseFile(tmpl, "content", name); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// Parse every template of the partials directory, named by its path without the extension
+func (engine *HTMLEngine) parsePartials(tmpl *template.Template) error {
+	err := fs.WalkDir(engine.fsys, engine.opt.Partials, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || path.Ext(p) != engine.opt.Extension {
+			return nil
+		}
+
+		partial := strings.TrimSuffix(p, engine.opt.Extension)
+		return engine.parseFile(tmpl, partial, partial)
+	})
+
+	// Apps without partials are fine
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// Read the template file of the passed view name and parse it into the set under the template name
+func (engine *HTMLEngine) parseFile(tmpl *template.Template, templateName, name string) error {
+	file := strings.TrimPrefix(name, "/") + engine.opt.Extension
+	if !fs.ValidPath(file) {
+		return fmt.Errorf("invalid view name %q", name)
+	}
+
+	content, err := fs.ReadFile(engine.fsys, file)
+	if err != nil {
+		return fmt.Errorf("view %q: %w", name, err)
+	}
+
+	if _, err := tmpl.New(templateName).Parse(string(content)); err != nil {
+		return fmt.Errorf("view %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// Render the view with the passed name through the app's view engine and send it as text/html
+// The app's `ViewLayout` wraps the view, unless another layout is passed, or an empty one for no layout
+// The view is rendered fully before anything is sent, so a template failing halfway through
+// gets a 500 response instead of a partial page
+// Example: res.Render("users/show", user)
+func (res *Res) Render(name string, data any, layout ...string) error {
+	if err := res.checkWritable(); err != nil {
+		return err
+	}
+
+	req := res.request()
+	if req == nil || req.app == nil || req.app.Views == nil {
+		log.Println("Error rendering view:", ErrNoViewEngine)
+		res.sendError(500, "Template Render Failed")
+		return ErrNoViewEngine
+	}
+
+	viewLayout := req.app.ViewLayout
+	if len(layout) > 0 {
+		viewLayout = layout[0]
+	}
+
+	var buf bytes.Buffer
+	if err := req.app.Views.Render(&buf, name, data, viewLayout); err != nil {
+		log.Printf("Error rendering view %q: %v", name, err)
+		res.sendError(500, "Template Render Failed")
+		return err
+	}
+
+	if res.ContentType == "" {
+		res.ContentType = "text/html; charset=utf-8"
+	}
+
+	return res.send(buf.Bytes())
+}
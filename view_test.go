@@ -0,0 +1,158 @@
+package zttp
+
+import (
+	"errors"
+	"html/template"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRender(t *testing.T) {
+	views := fstest.MapFS{
+		"layouts/main.html":   {Data: []byte(`<title>{{ block "title" . }}Default{{ end }}</title>{{ template "partials/nav" . }}<main>{{ template "content" . }}</main>`)},
+		"partials/nav.html":   {Data: []byte(`<nav>{{ upper .Site }}</nav>`)},
+		"users/show.html":     {Data: []byte(`{{ define "title" }}User {{ .Name }}{{ end }}<h1>{{ .Name }}</h1>`)},
+		"plain.html":          {Data: []byte(`<p>{{ .Name }}</p>`)},
+		"broken/halfway.html": {Data: []byte(`<p>before</p>{{ fail }}<p>after</p>`)},
+	}
+
+	app := NewApp()
+	app.Views = NewHTMLEngineFS(views, ViewOptions{
+		Funcs: template.FuncMap{
+			"upper": strings.ToUpper,
+			"fail":  func() (string, error) { return "", errors.New("failed") },
+		},
+	})
+	app.ViewLayout = "layouts/main"
+
+	data := map[string]string{"Name": "<Zakaria>", "Site": "zttp"}
+
+	app.Get("/user", func(req *Req, res *Res) {
+		res.Render("users/show", data)
+	})
+	app.Get("/plain", func(req *Req, res *Res) {
+		res.Render("plain", data, "")
+	})
+	app.Get("/halfway", func(req *Req, res *Res) {
+		res.Render("broken/halfway", data, "")
+	})
+	app.Get("/missing", func(req *Req, res *Res) {
+		res.Render("missing", data)
+	})
+	app.Get("/traversal", func(req *Req, res *Res) {
+		res.Render("../secret", data)
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus string
+		expectedType   string
+		expectedBody   string
+	}{
+		{
+			name:           "Layout with blocks and partials",
+			path:           "/user",
+			expectedStatus: "HTTP/1.1 200 OK",
+			expectedType:   "text/html; charset=utf-8",
+			expectedBody:   "<title>User &lt;Zakaria&gt;</title><nav>ZTTP</nav><main><h1>&lt;Zakaria&gt;</h1></main>",
+		},
+		{
+			name:           "Without layout",
+			path:           "/plain",
+			expectedStatus: "HTTP/1.1 200 OK",
+			expectedType:   "text/html; charset=utf-8",
+			expectedBody:   "<p>&lt;Zakaria&gt;</p>",
+		},
+		{
+			name:           "Failing halfway through",
+			path:           "/halfway",
+			expectedStatus: "HTTP/1.1 500 Internal Server Error",
+			expectedType:   "text/plain; charset=utf-8",
+			expectedBody:   "Internal Server Error: Template Render Failed",
+		},
+		{
+			name:           "Missing view",
+			path:           "/missing",
+			expectedStatus: "HTTP/1.1 500 Internal Server Error",
+			expectedType:   "text/plain; charset=utf-8",
+			expectedBody:   "Internal Server Error: Template Render Failed",
+		},
+		{
+			name:           "Invalid view name",
+			path:           "/traversal",
+			expectedStatus: "HTTP/1.1 500 Internal Server Error",
+			expectedType:   "text/plain; charset=utf-8",
+			expectedBody:   "Internal Server Error: Template Render Failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := mockRequest(app, "GET", tt.path, "", nil)
+
+			if !strings.HasPrefix(response, tt.expectedStatus) {
+				t.Errorf("Expected status %q, got: %s", tt.expectedStatus, response)
+			}
+
+			if contentType := responseHeader(response, "Content-Type"); contentType != tt.expectedType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.expectedType, contentType)
+			}
+
+			_, body, _ := strings.Cut(response, "\r\n\r\n")
+			if body != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestRenderReload(t *testing.T) {
+	views := fstest.MapFS{
+		"index.html": {Data: []byte("v1")},
+	}
+
+	cached := NewHTMLEngineFS(views)
+	reloaded := NewHTMLEngineFS(views, ViewOptions{Reload: true})
+
+	render := func(engine *HTMLEngine) string {
+		var sb strings.Builder
+		if err := engine.Render(&sb, "index", nil, ""); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return sb.String()
+	}
+
+	render(cached)
+	render(reloaded)
+
+	views["index.html"] = &fstest.MapFile{Data: []byte("v2")}
+
+	if got := render(cached); got != "v1" {
+		t.Errorf("Expected the cached template, got %q", got)
+	}
+
+	if got := render(reloaded); got != "v2" {
+		t.Errorf("Expected the reloaded template, got %q", got)
+	}
+}
+
+func TestRenderWithoutEngine(t *testing.T) {
+	app := NewApp()
+
+	var renderErr error
+	app.Get("/", func(req *Req, res *Res) {
+		renderErr = res.Render("index", nil)
+	})
+
+	response := mockRequest(app, "GET", "/", "", nil)
+
+	if !errors.Is(renderErr, ErrNoViewEngine) {
+		t.Errorf("Expected ErrNoViewEngine, got %v", renderErr)
+	}
+
+	if !strings.HasPrefix(response, "HTTP/1.1 500 Internal Server Error") {
+		t.Errorf("Expected 500, got: %s", response)
+	}
+}